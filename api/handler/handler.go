@@ -35,9 +35,22 @@ func Handler(db *gorm.DB) http.HandlerFunc {
 	routes.PostRoutes(rGin)
 	routes.SavedRoutes(rGin)
 	routes.CommentRoutes(rGin)
+	routes.ReplyRoutes(rGin)
+	routes.CommentFilterRoutes(rGin)
+	routes.AudioRoutes(rGin)
+	routes.MediaRoutes(rGin)
 	routes.LikeRoutes(rGin)
+	routes.ReactionRoutes(rGin)
 	routes.FollowRoutes(rGin)
-	routes.ReplyRoutes(rGin)
+	routes.BlockRoutes(rGin)
+	routes.MuteRoutes(rGin)
+	routes.CloseFriendRoutes(rGin)
+	routes.SuggestionRoutes(rGin)
+	routes.RelationshipRoutes(rGin)
+	routes.HashtagRoutes(rGin)
+	routes.NotificationRoutes(rGin)
+	routes.TrendingRoutes(rGin)
+	routes.SearchRoutes(rGin)
 	routes.SchedulerRoutes(rGin)
 
 	// Return the Gin router as an http.HandlerFunc
@@ -5,12 +5,13 @@ import (
 	"net/http"
 	"pixi/api/handler"
 	"pixi/config"
-	"pixi/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Exported Handler function for Vercel
+// Exported Handler function for Vercel. The schema is migrated separately by
+// cmd/migrate, once per deploy, so requests never race on migrations or pay
+// for their table scans.
 func Handler(w http.ResponseWriter, r *http.Request) {
 	// Set Gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
@@ -22,18 +23,9 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Println("Successfully connected to the database.")
 
-	// Perform AutoMigrate for all models
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Post{},
-		&models.Save{},
-		&models.Comment{},
-		&models.Like{},
-		&models.Follow{},
-		&models.Reply{},
-	)
-	if err != nil {
-		log.Fatal("Failed to run AutoMigrate:", err)
+	// Set up the media storage backend; without one only uploads are refused
+	if _, err := config.ConnectStorage(); err != nil {
+		log.Println("Media storage is unavailable:", err)
 	}
 
 	// Initialize the Gin router and handle the request
@@ -0,0 +1,26 @@
+// Command migrate brings the database schema and data up to date. Run it once
+// per deploy, before the new version serves traffic, with the same PG*
+// environment variables as the API:
+//
+//	go run ./cmd/migrate
+package main
+
+import (
+	"log"
+	"pixi/config"
+	"pixi/models"
+)
+
+func main() {
+	// Connect to the database
+	db, err := config.ConnectDB()
+	if err != nil {
+		log.Fatal("Failed to connect to the database:", err)
+	}
+
+	// Run every schema and data migration
+	if err := models.Migrate(db); err != nil {
+		log.Fatal("Failed to migrate the database:", err)
+	}
+	log.Println("Database migrated successfully.")
+}
@@ -0,0 +1,57 @@
+// Command video-worker runs the video processing queue. Transcoding needs
+// ffmpeg and ffprobe and can take minutes, more than a Vercel function allows,
+// so the worker runs as its own long-lived process, on any host with ffmpeg,
+// the API's PG* variables and the same shared storage (STORAGE_DRIVER=s3):
+//
+//	go run ./cmd/video-worker
+//
+// It processes due jobs every VIDEO_WORKER_INTERVAL (10s by default) until
+// interrupted. Several workers may run at once; each job is claimed by one.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"pixi/config"
+	"pixi/controllers"
+	"pixi/utils"
+	"syscall"
+	"time"
+)
+
+const defaultWorkerInterval = 10 * time.Second
+
+func main() {
+	// Connect to the database
+	if _, err := config.ConnectDB(); err != nil {
+		log.Fatal("Failed to connect to the database:", err)
+	}
+
+	// Set up the media storage backend
+	if _, err := config.ConnectStorage(); err != nil {
+		log.Fatal("Failed to set up media storage:", err)
+	}
+
+	interval, err := time.ParseDuration(utils.GetEnv("VIDEO_WORKER_INTERVAL", defaultWorkerInterval.String()))
+	if err != nil || interval <= 0 {
+		interval = defaultWorkerInterval
+	}
+
+	// Finish the current batch before stopping on a deploy or Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Println("Video worker started, polling every", interval)
+	for {
+		controllers.ProcessVideoJobs()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Video worker stopped.")
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"pixi/storage"
+	"pixi/utils"
+	"sync"
+)
+
+// Storage is the backend uploaded media is kept in, set up by ConnectStorage
+var Storage storage.Storage
+
+var storageOnce sync.Once
+
+// ConnectStorage sets up the storage backend named by STORAGE_DRIVER:
+//   - local (default) keeps files in STORAGE_DIR, served under MEDIA_BASE_URL
+//   - s3 keeps them in S3_BUCKET at S3_ENDPOINT, an Amazon S3 or compatible
+//     service, signing with S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY
+//
+// Local storage is refused on Vercel, where the disk is read-only and not
+// shared between instances. When the backend cannot be set up, Storage is
+// left as a storage.Unavailable so only uploads fail, and the error is
+// returned.
+func ConnectStorage() (storage.Storage, error) {
+	backend, err := newStorage()
+	if err != nil {
+		Storage = &storage.Unavailable{Err: err}
+		return Storage, err
+	}
+	Storage = backend
+	return Storage, nil
+}
+
+// newStorage builds the backend named by STORAGE_DRIVER
+func newStorage() (storage.Storage, error) {
+	onVercel := utils.GetEnv("VERCEL", "") != ""
+	driver := utils.GetEnv("STORAGE_DRIVER", "")
+	if driver == "" && !onVercel {
+		driver = "local"
+	}
+
+	switch driver {
+	case "":
+		return nil, fmt.Errorf("STORAGE_DRIVER must be set on Vercel; use s3")
+	case "local":
+		if onVercel {
+			return nil, fmt.Errorf("local storage does not work on Vercel; set STORAGE_DRIVER=s3")
+		}
+		return &storage.Local{
+			Dir:     utils.GetEnv("STORAGE_DIR", "uploads"),
+			BaseURL: utils.GetEnv("MEDIA_BASE_URL", "/uploads"),
+		}, nil
+	case "s3":
+		s3 := &storage.S3{
+			Endpoint:  utils.GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:    utils.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:    utils.GetEnv("S3_BUCKET", ""),
+			AccessKey: utils.GetEnv("S3_ACCESS_KEY_ID", ""),
+			SecretKey: utils.GetEnv("S3_SECRET_ACCESS_KEY", ""),
+			PathStyle: utils.GetEnv("S3_PATH_STYLE", "false") == "true",
+			PublicURL: utils.GetEnv("MEDIA_BASE_URL", ""),
+		}
+		if s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			return nil, fmt.Errorf("S3 storage needs S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+		}
+		return s3, nil
+	}
+	return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+}
+
+// GetStorage returns the storage backend, setting it up on first use when
+// ConnectStorage has not been called. It is a storage.Unavailable when the
+// backend could not be set up.
+func GetStorage() storage.Storage {
+	storageOnce.Do(func() {
+		if Storage == nil {
+			if _, err := ConnectStorage(); err != nil {
+				log.Println("Media storage is unavailable:", err)
+			}
+		}
+	})
+	return Storage
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/storage"
+	"pixi/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Voice comment limits, unless MAX_AUDIO_BYTES, MAX_AUDIO_SECONDS or
+// AUDIO_ORPHAN_TTL say otherwise
+const (
+	defaultMaxAudioBytes   = 10 << 20
+	defaultMaxAudioSeconds = 60
+	defaultAudioOrphanTTL  = 24 * time.Hour // Uploads not attached to a comment within this are removed
+	audioPeakCount         = 64             // Waveform bars stored per clip
+)
+
+// envInt reads a positive integer setting, falling back to def when it is unset or invalid
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(utils.GetEnv(key, strconv.Itoa(def)))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// UploadAudio stores a voice recording sent as the multipart field "audio".
+// The file must be WAV, MP3, Ogg (Opus or Vorbis) or M4A and no longer than
+// MAX_AUDIO_SECONDS. Attach the returned clip to a comment with its AudioID.
+func UploadAudio(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+	maxBytes := int64(envInt("MAX_AUDIO_BYTES", defaultMaxAudioBytes))
+
+	// Cap the request body so oversized files are not read in full; leave room for the multipart headers
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+	header, err := c.FormFile("audio")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Audio file is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An audio file is required in the audio field", "details": err.Error()})
+		return
+	}
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Audio file is too large"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read audio file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read audio file", "details": err.Error()})
+		return
+	}
+
+	// Validate the format from the file's contents rather than its name or declared type
+	info, err := utils.ProbeAudio(data, audioPeakCount)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, utils.ErrUnsupportedAudio) {
+			status = http.StatusUnsupportedMediaType
+		}
+		c.JSON(status, gin.H{"error": "Invalid audio file", "details": err.Error()})
+		return
+	}
+	if info.Duration > time.Duration(envInt("MAX_AUDIO_SECONDS", defaultMaxAudioSeconds))*time.Second {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audio is too long"})
+		return
+	}
+
+	// Store the file, then record the clip
+	store := config.GetStorage()
+	key, err := storage.NewKey("audio/"+strconv.FormatUint(uint64(viewer), 10), "."+info.Format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store audio", "details": err.Error()})
+		return
+	}
+	if err := store.Put(c.Request.Context(), key, bytes.NewReader(data), int64(len(data)), info.ContentType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store audio", "details": err.Error()})
+		return
+	}
+
+	clip := models.AudioClip{
+		UserID:      viewer,
+		Key:         key,
+		URL:         store.URL(key),
+		Format:      info.Format,
+		ContentType: info.ContentType,
+		Size:        int64(len(data)),
+		DurationMs:  info.Duration.Milliseconds(),
+		Peaks:       info.Peaks,
+	}
+	if err := db.Create(&clip).Error; err != nil {
+		deleteStoredObjects([]string{key})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save audio", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Audio uploaded successfully", "audio": clip})
+}
+
+// DeleteAudio removes one of the logged in user's uploads that was never
+// attached to a comment. Attached clips go when their comment is deleted.
+func DeleteAudio(c *gin.Context) {
+	db := config.GetDB()
+
+	var clip models.AudioClip
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), viewerID(c)).First(&clip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audio not found"})
+		return
+	}
+
+	var attached int64
+	if err := db.Model(&models.Comment{}).Where("audio_id = ?", clip.ID).Count(&attached).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete audio", "details": err.Error()})
+		return
+	}
+	if attached > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Audio is attached to a comment; delete the comment instead"})
+		return
+	}
+
+	if err := db.Delete(&clip).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete audio", "details": err.Error()})
+		return
+	}
+	deleteStoredObjects([]string{clip.Key})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Audio deleted successfully"})
+}
+
+// findAttachableAudio loads a clip the user uploaded that no comment uses yet
+func findAttachableAudio(db *gorm.DB, audioID, userID uint) (*models.AudioClip, error) {
+	var clip models.AudioClip
+	if err := db.Where("id = ? AND user_id = ?", audioID, userID).
+		Where("NOT EXISTS (SELECT 1 FROM comments WHERE comments.audio_id = audio_clips.id)").
+		First(&clip).Error; err != nil {
+		return nil, err
+	}
+	return &clip, nil
+}
+
+// deleteAudioClips removes the clip rows with the given IDs inside tx and
+// returns their storage keys, to delete once the transaction commits
+func deleteAudioClips(tx *gorm.DB, ids []uint) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var keys []string
+	if err := tx.Model(&models.AudioClip{}).Where("id IN ?", ids).Pluck("key", &keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, tx.Where("id IN ?", ids).Delete(&models.AudioClip{}).Error
+}
+
+// CleanupOrphanedAudio deletes uploads never attached to a comment once they
+// are older than AUDIO_ORPHAN_TTL, along with their files
+func CleanupOrphanedAudio() {
+	db := config.GetDB()
+
+	log.Println("Running CleanupOrphanedAudio at:", time.Now().UTC())
+
+	ttl, err := time.ParseDuration(utils.GetEnv("AUDIO_ORPHAN_TTL", defaultAudioOrphanTTL.String()))
+	if err != nil || ttl <= 0 {
+		ttl = defaultAudioOrphanTTL
+	}
+
+	var ids []uint
+	if err := db.Model(&models.AudioClip{}).
+		Where("created_at < ?", time.Now().Add(-ttl)).
+		Where("NOT EXISTS (SELECT 1 FROM comments WHERE comments.audio_id = audio_clips.id)").
+		Pluck("id", &ids).Error; err != nil {
+		log.Println("Error finding orphaned audio:", err)
+		return
+	}
+
+	keys, err := deleteAudioClips(db, ids)
+	if err != nil {
+		log.Println("Error deleting orphaned audio:", err)
+		return
+	}
+	deleteStoredObjects(keys)
+	log.Println("Deleted orphaned audio (clips:", len(keys), ")")
+}
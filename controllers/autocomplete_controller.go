@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Autocomplete limits and ranking weights
+const (
+	maxAutocompleteLimit      = 20
+	autocompletePoolSize      = 50 // Prefix matches ranked per request
+	autocompleteFollowBoost   = 100
+	autocompleteFollowerBoost = 20
+	autocompleteMaxActivity   = 50                  // Cap on the interaction boost so it never outweighs a follow
+	autocompleteActivityAge   = 90 * 24 * time.Hour // Interactions older than this no longer boost
+)
+
+// autocompleteUser is one typeahead suggestion, kept small for fast responses
+type autocompleteUser struct {
+	ID           uint
+	Username     string
+	FullName     string
+	ProfileImage string
+	Following    bool
+	score        int64
+	followers    int64
+}
+
+// AutocompleteUsers suggests users whose username or full name starts with
+// the q query parameter, ranking accounts the viewer follows or interacts with first
+func AutocompleteUsers(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "@"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "8"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxAutocompleteLimit {
+		limit = maxAutocompleteLimit
+	}
+
+	// Prefix matches on the indexed lowercase columns, most followed first
+	usernameMatch, usernameArgs := prefixCondition(db, "users.username", prefix)
+	fullNameMatch, fullNameArgs := prefixCondition(db, "users.full_name", prefix)
+	var users []models.User
+	if err := db.Select("id", "username", "full_name", "profile_image", "follower_count").
+		Scopes(notBlocked(viewer, "users.id")).
+		Where("users.id <> ?", viewer).
+		Where(db.Where(usernameMatch, usernameArgs...).Or(fullNameMatch, fullNameArgs...)).
+		Order("follower_count DESC").Order("id").Limit(autocompletePoolSize).
+		Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to autocomplete users", "details": err.Error()})
+		return
+	}
+
+	results := make([]*autocompleteUser, len(users))
+	byID := make(map[uint]*autocompleteUser, len(users))
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		results[i] = &autocompleteUser{
+			ID:           user.ID,
+			Username:     user.Username,
+			FullName:     user.FullName,
+			ProfileImage: user.ProfileImage,
+			followers:    user.FollowerCount,
+		}
+		byID[user.ID] = results[i]
+		ids[i] = user.ID
+	}
+
+	// Boost the viewer's own network
+	if viewer != 0 && len(ids) > 0 {
+		if err := boostAutocomplete(db, viewer, ids, byID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to autocomplete users", "details": err.Error()})
+			return
+		}
+	}
+
+	// Exact username matches first, then the boosted score, then popularity
+	sort.SliceStable(results, func(i, j int) bool {
+		exactI, exactJ := strings.ToLower(results[i].Username) == prefix, strings.ToLower(results[j].Username) == prefix
+		if exactI != exactJ {
+			return exactI
+		}
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].followers > results[j].followers
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": results})
+}
+
+// boostAutocomplete scores candidates by the viewer's follows and recent
+// interactions with them within autocompleteActivityAge: likes and comments
+// on their posts and mentions
+func boostAutocomplete(db *gorm.DB, viewer uint, ids []uint, byID map[uint]*autocompleteUser) error {
+	// Accounts the viewer follows
+	var followingIDs []uint
+	if err := db.Model(&models.Follow{}).
+		Where("follower_id = ? AND following_id IN ? AND status = ?", viewer, ids, models.FollowStatusAccepted).
+		Pluck("following_id", &followingIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range followingIDs {
+		byID[id].Following = true
+		byID[id].score += autocompleteFollowBoost
+	}
+
+	// Accounts following the viewer
+	var followerIDs []uint
+	if err := db.Model(&models.Follow{}).
+		Where("following_id = ? AND follower_id IN ? AND status = ?", viewer, ids, models.FollowStatusAccepted).
+		Pluck("follower_id", &followerIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range followerIDs {
+		byID[id].score += autocompleteFollowerBoost
+	}
+
+	// Recent interactions, one point each
+	since := time.Now().Add(-autocompleteActivityAge)
+	var activity []suggestionCandidate
+	if err := db.Raw(
+		"SELECT user_id, SUM(total) AS total FROM ("+
+			"SELECT posts.user_id AS user_id, COUNT(*) AS total FROM likes JOIN posts ON posts.id = likes.post_id WHERE likes.user_id = ? AND posts.user_id IN ? AND likes.created_at >= ? GROUP BY posts.user_id"+
+			" UNION ALL "+
+			"SELECT posts.user_id AS user_id, COUNT(*) AS total FROM comments JOIN posts ON posts.id = comments.post_id WHERE comments.user_id = ? AND posts.user_id IN ? AND comments.created_at >= ? GROUP BY posts.user_id"+
+			" UNION ALL "+
+			"SELECT mentioned_id AS user_id, COUNT(*) AS total FROM mentions WHERE mentioner_id = ? AND mentioned_id IN ? AND created_at >= ? GROUP BY mentioned_id"+
+			") AS activity GROUP BY user_id",
+		viewer, ids, since, viewer, ids, since, viewer, ids, since,
+	).Scan(&activity).Error; err != nil {
+		return err
+	}
+	for _, a := range activity {
+		if result, ok := byID[a.UserID]; ok {
+			if a.Total > autocompleteMaxActivity {
+				a.Total = autocompleteMaxActivity
+			}
+			result.score += a.Total
+		}
+	}
+	return nil
+}
+
+// prefixCondition matches a lowercased column against prefix in a way the
+// prefix indexes from models.PrepareSearch can serve: LIKE on Postgres with
+// text_pattern_ops, and a range comparison elsewhere
+func prefixCondition(db *gorm.DB, column, prefix string) (string, []interface{}) {
+	if db.Dialector.Name() == "postgres" {
+		return "LOWER(" + column + ") LIKE ? ESCAPE '\\'", []interface{}{utils.LikePrefix(prefix)}
+	}
+	return "LOWER(" + column + ") >= ? AND LOWER(" + column + ") < ?", []interface{}{prefix, prefix + "\U0010FFFF"}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetBlocks lists the users the logged in user has blocked
+func GetBlocks(c *gin.Context) {
+	db := config.GetDB()
+	var blocks []models.Block
+
+	if err := db.Preload("Blocked", publicUserColumns).Where("blocker_id = ?", viewerID(c)).Order("created_at DESC").Find(&blocks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blocked users", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+// CreateBlock blocks a user and removes any follows and close friends entries between the two accounts
+func CreateBlock(c *gin.Context) {
+	var newBlock models.Block
+
+	// Binds the request body to newBlock object
+	if err := c.ShouldBindJSON(&newBlock); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	// The logged in user is always the blocker
+	newBlock.BlockerID = viewerID(c)
+
+	// Validate required fields
+	if newBlock.BlockedID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Blocked ID is required"})
+		return
+	}
+
+	// Check if a user is trying to block themselves
+	if newBlock.BlockerID == newBlock.BlockedID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A user cannot block themselves"})
+		return
+	}
+
+	// Get the DB connection
+	db := config.GetDB()
+
+	// Ensure the user to block exists
+	var blocked models.User
+	if err := db.First(&blocked, newBlock.BlockedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User to block not found"})
+		return
+	}
+
+	// Check if the block already exists
+	var existingBlock models.Block
+	err := db.Where("blocker_id = ? AND blocked_id = ?", newBlock.BlockerID, newBlock.BlockedID).First(&existingBlock).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking block", "details": err.Error()})
+		return
+	}
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User already blocked"})
+		return
+	}
+
+	// Create the block and drop follows in both directions together
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newBlock).Error; err != nil {
+			return err
+		}
+
+		var follows []models.Follow
+		if err := tx.Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)",
+			newBlock.BlockerID, newBlock.BlockedID, newBlock.BlockedID, newBlock.BlockerID).Find(&follows).Error; err != nil {
+			return err
+		}
+		for i := range follows {
+			if err := removeFollow(tx, &follows[i]); err != nil {
+				return err
+			}
+		}
+
+		// Neither user stays on the other's close friends list
+		return tx.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
+			newBlock.BlockerID, newBlock.BlockedID, newBlock.BlockedID, newBlock.BlockerID).Delete(&models.CloseFriend{}).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "User blocked successfully", "block": newBlock})
+}
+
+// DeleteBlock unblocks a user by their user ID. Follows removed by the block are not restored.
+func DeleteBlock(c *gin.Context) {
+	db := config.GetDB()
+
+	result := db.Where("blocker_id = ? AND blocked_id = ?", viewerID(c), c.Param("userID")).Delete(&models.Block{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Block not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked successfully"})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetCloseFriends lists the logged in user's close friends
+func GetCloseFriends(c *gin.Context) {
+	db := config.GetDB()
+	var closeFriends []models.CloseFriend
+
+	if err := db.Preload("Friend", publicUserColumns).Where("user_id = ?", viewerID(c)).Order("created_at DESC").Find(&closeFriends).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve close friends", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, closeFriends)
+}
+
+// CreateCloseFriend adds a user to the logged in user's close friends list
+func CreateCloseFriend(c *gin.Context) {
+	var newCloseFriend models.CloseFriend
+
+	// Binds the request body to newCloseFriend object
+	if err := c.ShouldBindJSON(&newCloseFriend); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	// The list always belongs to the logged in user
+	newCloseFriend.UserID = viewerID(c)
+
+	// Validate required fields
+	if newCloseFriend.FriendID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Friend ID is required"})
+		return
+	}
+
+	// Check if a user is trying to add themselves
+	if newCloseFriend.UserID == newCloseFriend.FriendID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A user cannot add themselves to close friends"})
+		return
+	}
+
+	// Get the DB connection
+	db := config.GetDB()
+
+	// Ensure the friend exists and is not blocked either way
+	var friend models.User
+	if err := db.First(&friend, newCloseFriend.FriendID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	blocked, err := isBlocked(db, newCloseFriend.UserID, newCloseFriend.FriendID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking block", "details": err.Error()})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Check if the user is already on the list
+	var existingCloseFriend models.CloseFriend
+	err = db.Where("user_id = ? AND friend_id = ?", newCloseFriend.UserID, newCloseFriend.FriendID).First(&existingCloseFriend).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking close friends", "details": err.Error()})
+		return
+	}
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a close friend"})
+		return
+	}
+
+	// Save the new close friend to the database
+	if err := db.Create(&newCloseFriend).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add close friend", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Close friend added successfully", "close_friend": newCloseFriend})
+}
+
+// DeleteCloseFriend removes a user from the logged in user's close friends list by their user ID
+func DeleteCloseFriend(c *gin.Context) {
+	db := config.GetDB()
+
+	result := db.Where("user_id = ? AND friend_id = ?", viewerID(c), c.Param("userID")).Delete(&models.CloseFriend{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove close friend", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Close friend not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Close friend removed successfully"})
+}
@@ -4,12 +4,48 @@ import (
 	"net/http"
 	"pixi/config"
 	"pixi/models"
+	"pixi/utils"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// Get comments by post id
+// Comment sort modes and page limits
+const (
+	commentSortNewest   = "newest"
+	commentSortOldest   = "oldest"
+	commentSortTop      = "top" // Most liked first
+	maxCommentLimit     = 50
+	maxThreadLimit      = 200
+	defaultReplyPreview = 2
+	maxReplyPreview     = 10
+
+	// defaultMaxCommentDepth is the deepest reply level unless MAX_COMMENT_DEPTH
+	// says otherwise; replies below it join their parent's level instead
+	defaultMaxCommentDepth = 8
+)
+
+// commentPage describes which page of a post's comments to load. Newest and
+// oldest pages continue after LastID; top pages, whose order shifts as likes
+// come in, continue from Offset.
+type commentPage struct {
+	Sort         string
+	LastID       int
+	Offset       int
+	Limit        int
+	ReplyPreview int // Replies loaded with each comment
+}
+
+// defaultCommentPage is the first page shown with a post
+var defaultCommentPage = commentPage{Sort: commentSortNewest, Limit: 10, ReplyPreview: defaultReplyPreview}
+
+// commentPageParams are the query parameters that select a page of comments
+var commentPageParams = []string{"sort", "last_comment_id", "offset", "limit", "reply_preview"}
+
+// Get comments by post id. Requests using any of the paging parameters get
+// {"comments": [...], "sort": ...}; requests without them get the first page
+// as a bare array, as the endpoint returned before it was paged.
 func GetCommentsByPostID(c *gin.Context) {
 	postID, err := strconv.Atoi(c.Param("postID"))
 	if err != nil || postID <= 0 {
@@ -17,20 +53,316 @@ func GetCommentsByPostID(c *gin.Context) {
 		return
 	}
 
-	// Declare a slice to hold comments
+	// Retrieve query parameters for paging and sorting
+	page, ok := parseCommentPage(c)
+	if !ok {
+		return
+	}
+
+	// Comments on posts the viewer cannot see stay hidden
 	db := config.GetDB()
-	var comments []models.Comment
+	var post models.Post
+	if !findVisiblePost(c, db, postID, &post) {
+		return
+	}
 
-	// Retrieve all comments for the given post from the database
-	if err := db.Preload("User").Where("post_id = ?", postID).Find(&comments).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+	// Retrieve one page of comments with a preview of their replies
+	comments, err := loadComments(db, viewerID(c), post.ID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments", "details": err.Error()})
 		return
 	}
 
-	// Return the comments as JSON
+	// Return the comments as JSON, in the shape the client asked for
+	for _, param := range commentPageParams {
+		if _, paged := c.GetQuery(param); paged {
+			c.JSON(http.StatusOK, gin.H{"comments": comments, "sort": page.Sort})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, comments)
 }
 
+// parseCommentPage reads the sort, last_comment_id, offset, limit and
+// reply_preview query parameters, writing a 400 when one is invalid
+func parseCommentPage(c *gin.Context) (commentPage, bool) {
+	page := commentPage{Sort: c.DefaultQuery("sort", commentSortNewest)}
+	if page.Sort != commentSortNewest && page.Sort != commentSortOldest && page.Sort != commentSortTop {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be newest, oldest or top"})
+		return page, false
+	}
+
+	var err error
+	if page.LastID, err = strconv.Atoi(c.DefaultQuery("last_comment_id", "0")); err != nil || page.LastID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_comment_id"})
+		return page, false
+	}
+	if page.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil || page.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return page, false
+	}
+	if page.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "20")); err != nil || page.Limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return page, false
+	}
+	if page.Limit > maxCommentLimit {
+		page.Limit = maxCommentLimit
+	}
+	if page.ReplyPreview, err = strconv.Atoi(c.DefaultQuery("reply_preview", strconv.Itoa(defaultReplyPreview))); err != nil || page.ReplyPreview < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reply_preview"})
+		return page, false
+	}
+	if page.ReplyPreview > maxReplyPreview {
+		page.ReplyPreview = maxReplyPreview
+	}
+	return page, true
+}
+
+// loadComments loads one page of a post's top-level comments that the viewer
+// may see, each with its first replies. Pinned comments lead the first page
+// and are left out of the rest.
+func loadComments(db *gorm.DB, viewer uint, postID uint, page commentPage) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	if page.LastID == 0 && page.Offset == 0 {
+		if err := db.Preload("User").Preload("Mentions").Preload("Audio").Scopes(visibleComments(viewer)).
+			Where("post_id = ? AND parent_id IS NULL AND pinned_at IS NOT NULL", postID).
+			Order("pinned_at").Find(&comments).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	query := db.Preload("User").Preload("Mentions").Preload("Audio").
+		Scopes(visibleComments(viewer)).
+		Where("post_id = ? AND parent_id IS NULL AND pinned_at IS NULL", postID)
+
+	switch page.Sort {
+	case commentSortOldest:
+		query = query.Where("id > ?", page.LastID).Order("id")
+	case commentSortTop:
+		query = query.Order("like_count DESC").Order("reply_count DESC").Order("id").Offset(page.Offset)
+	default:
+		if page.LastID > 0 {
+			query = query.Where("id < ?", page.LastID)
+		}
+		query = query.Order("id DESC")
+	}
+
+	var rest []models.Comment
+	if err := query.Limit(page.Limit).Find(&rest).Error; err != nil {
+		return nil, err
+	}
+	comments = append(comments, rest...)
+	if err := attachReplyPreviews(db, viewer, comments, page.ReplyPreview); err != nil {
+		return nil, err
+	}
+	return comments, attachCommentReactions(db, viewer, comments)
+}
+
+// attachReplyPreviews fills in the first n direct replies the viewer may see
+// on each comment, oldest first; ReplyCount tells clients whether there are more
+func attachReplyPreviews(db *gorm.DB, viewer uint, comments []models.Comment, n int) error {
+	if len(comments) == 0 || n == 0 {
+		return nil
+	}
+
+	commentIDs := make([]uint, len(comments))
+	for i := range comments {
+		commentIDs[i] = comments[i].ID
+		comments[i].Replies = []models.Comment{}
+	}
+
+	// Rank the replies within each comment and keep the first n
+	ranked := db.Model(&models.Comment{}).
+		Select("comments.id, ROW_NUMBER() OVER (PARTITION BY comments.parent_id ORDER BY comments.id) AS preview_rank").
+		Scopes(visibleComments(viewer)).
+		Where("comments.parent_id IN ?", commentIDs)
+	var replyIDs []uint
+	if err := db.Table("(?) AS ranked", ranked).Where("preview_rank <= ?", n).Pluck("id", &replyIDs).Error; err != nil {
+		return err
+	}
+	if len(replyIDs) == 0 {
+		return nil
+	}
+
+	var replies []models.Comment
+	if err := db.Preload("User").Preload("Mentions").Preload("Audio").Where("id IN ?", replyIDs).Order("id").Find(&replies).Error; err != nil {
+		return err
+	}
+
+	byComment := make(map[uint]int, len(comments))
+	for i := range comments {
+		byComment[comments[i].ID] = i
+	}
+	for _, reply := range replies {
+		if i, ok := byComment[*reply.ParentID]; ok {
+			comments[i].Replies = append(comments[i].Replies, reply)
+		}
+	}
+	return nil
+}
+
+// GetRepliesByCommentID pages through the direct replies to a comment, oldest
+// first, so each branch of a thread can load more replies on its own
+func GetRepliesByCommentID(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	// Retrieve query parameters for infinite scroll
+	lastReplyID, err := strconv.Atoi(c.DefaultQuery("last_reply_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_reply_id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxCommentLimit {
+		limit = maxCommentLimit
+	}
+	preview, err := strconv.Atoi(c.DefaultQuery("reply_preview", "0"))
+	if err != nil || preview < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reply_preview"})
+		return
+	}
+	if preview > maxReplyPreview {
+		preview = maxReplyPreview
+	}
+
+	comment, ok := findVisibleComment(c, db, c.Param("commentID"))
+	if !ok {
+		return
+	}
+
+	// Fetch replies after the last loaded reply, optionally with a preview of the next level
+	replies := []models.Comment{}
+	if err := db.Preload("User").Preload("Mentions").Preload("Audio").Scopes(visibleComments(viewer)).
+		Where("parent_id = ? AND id > ?", comment.ID, lastReplyID).
+		Order("id").Limit(limit).Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies", "details": err.Error()})
+		return
+	}
+	if err := attachReplyPreviews(db, viewer, replies, preview); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies", "details": err.Error()})
+		return
+	}
+	if err := attachCommentReactions(db, viewer, replies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replies": replies, "reply_count": comment.ReplyCount})
+}
+
+// GetCommentThread returns a comment with its replies nested below it, read
+// in one query through the materialized path. The tree is cut off after limit
+// comments or max_depth levels; branches are continued through the replies
+// endpoint using each comment's ReplyCount.
+func GetCommentThread(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxThreadLimit {
+		limit = maxThreadLimit
+	}
+	maxDepth, err := strconv.Atoi(c.DefaultQuery("max_depth", "0"))
+	if err != nil || maxDepth < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_depth"})
+		return
+	}
+
+	comment, ok := findVisibleComment(c, db, c.Param("commentID"))
+	if !ok {
+		return
+	}
+
+	// Read the subtree depth first; parents always sort before their replies
+	query := db.Preload("User").Preload("Mentions").Preload("Audio").Scopes(visibleComments(viewer)).
+		Where("post_id = ? AND path LIKE ? ESCAPE '\\' AND id <> ?", comment.PostID, utils.LikePrefix(comment.Path), comment.ID)
+	if maxDepth > 0 {
+		query = query.Where("depth <= ?", comment.Depth+maxDepth)
+	}
+	var descendants []models.Comment
+	if err := query.Order("path").Limit(limit).Find(&descendants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve thread", "details": err.Error()})
+		return
+	}
+
+	thread := []models.Comment{nestComments(*comment, descendants)}
+	if err := attachCommentReactions(db, viewer, thread); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve thread", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"thread": thread[0]})
+}
+
+// nestComments arranges comments read in path order under root. Replies whose
+// parent was filtered out, because the viewer blocked or muted its author,
+// are dropped along with it.
+func nestComments(root models.Comment, comments []models.Comment) models.Comment {
+	children := map[uint][]int{}
+	present := map[uint]bool{root.ID: true}
+	for i := range comments {
+		parent := *comments[i].ParentID
+		if !present[parent] {
+			continue
+		}
+		present[comments[i].ID] = true
+		children[parent] = append(children[parent], i)
+	}
+
+	var build func(comment models.Comment) models.Comment
+	build = func(comment models.Comment) models.Comment {
+		comment.Replies = []models.Comment{}
+		for _, i := range children[comment.ID] {
+			comment.Replies = append(comment.Replies, build(comments[i]))
+		}
+		return comment
+	}
+	return build(root)
+}
+
+// findVisibleComment loads a comment, writing a 404 when it does not exist or
+// the viewer may not see it or its post
+func findVisibleComment(c *gin.Context, db *gorm.DB, commentID interface{}) (*models.Comment, bool) {
+	var comment models.Comment
+	if err := db.Preload("User").Preload("Mentions").Preload("Audio").Scopes(visibleComments(viewerID(c))).First(&comment, commentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+	var post models.Post
+	if !findVisiblePost(c, db, comment.PostID, &post) {
+		return nil, false
+	}
+	return &comment, true
+}
+
+// visibleComments hides comments from users on the other side of a block,
+// from users whose comments the viewer muted, and comments the post's author
+// hid unless the viewer wrote them or owns the post
+func visibleComments(viewer uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(notBlocked(viewer, "comments.user_id"), notMuted(viewer, "comments.user_id", "mute_comments")).
+			Where("(comments.hidden = ? OR comments.user_id = ? OR comments.post_id IN (SELECT id FROM posts WHERE user_id = ?))", false, viewer, viewer)
+	}
+}
+
+// commentDepthLimit reads MAX_COMMENT_DEPTH, the deepest reply level allowed
+func commentDepthLimit() int {
+	depth, err := strconv.Atoi(utils.GetEnv("MAX_COMMENT_DEPTH", strconv.Itoa(defaultMaxCommentDepth)))
+	if err != nil || depth < 0 {
+		return defaultMaxCommentDepth
+	}
+	return depth
+}
+
 // CreateComment handles the creation of a new comment
 func CreateComment(c *gin.Context) {
 	var newComment models.Comment
@@ -42,50 +374,150 @@ func CreateComment(c *gin.Context) {
 		return
 	}
 
-	// Assign the UserID from the context to the new post
-	newComment.UserID = userID.(uint)
-
 	// Bind the request body to newComment object
 	if err := c.ShouldBindJSON(&newComment); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
-	// Validate required fields
-	if newComment.UserID == 0 || newComment.PostID == 0 || newComment.Author == "" || newComment.Content == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID, Post ID, and Content are required"})
+	// The ID and commenter are set server-side, never taken from the client
+	newComment.ID = 0
+	newComment.UserID = userID.(uint)
+
+	// Mentions are parsed from the content, and threading and counters maintained server-side, never taken from the client
+	newComment.Mentions, newComment.Replies = nil, nil
+	newComment.LikeCount, newComment.ReplyCount = 0, 0
+	newComment.Depth, newComment.Path = 0, ""
+	newComment.PinnedAt, newComment.Hidden, newComment.HiddenReason = nil, false, ""
+	newComment.Audio, newComment.AudioContent = nil, ""
+
+	// Validate required fields; a voice comment needs no text
+	if newComment.UserID == 0 || newComment.PostID == 0 || (newComment.Content == "" && newComment.AudioID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID, Post ID, and Content or AudioID are required"})
 		return
 	}
 
-	// Get the DB connection
+	// Comments carry their commenter's username, whatever the client sent
+	var user models.User
+	if err := config.GetDB().Select("id", "username").First(&user, newComment.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	newComment.Author = user.Username
+
+	// Check the comment against its post and save it
+	if !saveComment(c, &newComment) {
+		return
+	}
+
+	// Respond with the created comment
+	c.JSON(http.StatusCreated, gin.H{"message": "Comment created successfully", "comment": newComment})
+}
+
+// saveComment checks a new comment against its post's visibility, comment
+// policy and filters, threads it under its parent and saves it with its
+// mentions and counters, writing an error response when it cannot
+func saveComment(c *gin.Context, newComment *models.Comment) bool {
 	db := config.GetDB()
 
+	// Voice comments use a clip the commenter uploaded through POST /audio that is not attached elsewhere
+	if newComment.AudioID != nil {
+		clip, err := findAttachableAudio(db, *newComment.AudioID, newComment.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Audio not found or already attached to a comment"})
+			return false
+		}
+		newComment.Audio, newComment.AudioContent = clip, clip.URL
+	}
+
 	// Ensure the post exists before creating a comment
 	var post models.Post
-	if err := db.First(&post, newComment.PostID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		return
+	if !findVisiblePost(c, db, newComment.PostID, &post) {
+		return false
+	}
+
+	// Respect the author's comment policy
+	allowed, reason, err := canComment(db, &post, newComment.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking comment policy", "details": err.Error()})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return false
+	}
+
+	// Comments matching the post author's filters are hidden straight away
+	filtered, err := matchCommentFilters(db, &post, newComment.UserID, newComment.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking comment filters", "details": err.Error()})
+		return false
+	}
+	if filtered {
+		newComment.Hidden, newComment.HiddenReason = true, models.HiddenByFilter
+	}
+
+	// Replies must stay on the parent's post; replies past the depth limit join the parent's level
+	var parent *models.Comment
+	if newComment.ParentID != nil {
+		parent = &models.Comment{}
+		if err := db.Scopes(notBlocked(newComment.UserID, "comments.user_id")).
+			Where("id = ? AND post_id = ?", *newComment.ParentID, newComment.PostID).First(parent).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+			return false
+		}
+		if parent.Depth >= commentDepthLimit() && parent.ParentID != nil {
+			grandparent := &models.Comment{}
+			if err := db.First(grandparent, *parent.ParentID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+				return false
+			}
+			parent = grandparent
+		}
+		newComment.ParentID = &parent.ID
+		newComment.Depth = parent.Depth + 1
 	}
 
-	// Save the new comment to the database
-	if err := db.Create(&newComment).Error; err != nil {
+	// Save the new comment with its mentions and bump the post's and parent's counts together, unless it is hidden
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Audio").Create(newComment).Error; err != nil {
+			return err
+		}
+		parentPath := ""
+		if parent != nil {
+			parentPath = parent.Path
+		}
+		newComment.Path = models.CommentPath(parentPath, newComment.ID)
+		if err := tx.Model(newComment).Update("path", newComment.Path).Error; err != nil {
+			return err
+		}
+		source := mentionSource{AuthorID: newComment.UserID, PostID: newComment.PostID, CommentID: &newComment.ID}
+		mentions, err := syncMentions(tx, source, newComment.Content, !newComment.Hidden)
+		if err != nil {
+			return err
+		}
+		newComment.Mentions = mentions
+		if newComment.Hidden {
+			return nil
+		}
+		return adjustCommentCounters(tx, newComment, 1)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving comment", "details": err.Error()})
-		return
+		return false
 	}
 
-	// Respond with the created comment
-	c.JSON(http.StatusCreated, gin.H{"message": "Comment created successfully", "comment": newComment})
+	return true
 }
 
-// UpdateComment updates an existing comment
+// UpdateComment updates the content of an existing comment; only its
+// commenter may edit it
 func UpdateComment(c *gin.Context) {
 	db := config.GetDB()
-	commentID := c.Param("id")
+	commentID := c.Param("commentID")
 
 	// Bind the input JSON data to a map to handle specific fields
 	var input struct {
-		Author  *string `json:"Author"` // Nullable Author
-		Content string  `json:"Content"`
+		Content string `json:"Content"`
 	}
 
 	// Bind incoming JSON request data
@@ -101,18 +533,48 @@ func UpdateComment(c *gin.Context) {
 		return
 	}
 
-	// Update the fields if they are provided in the request
-	if input.Content != "" {
-		existingComment.Content = input.Content
+	// Only the commenter may edit the comment
+	if existingComment.UserID != viewerID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the commenter can update this comment"})
+		return
 	}
 
-	// If an Author is provided, update it; otherwise, leave it as it is
-	if input.Author != nil {
-		existingComment.Author = *input.Author
+	// Update the fields if they are provided in the request, re-checking new content against the post author's filters
+	wasHidden := existingComment.Hidden
+	if input.Content != "" && input.Content != existingComment.Content {
+		existingComment.Content = input.Content
+
+		var post models.Post
+		if err := db.Select("id", "user_id").First(&post, existingComment.PostID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		filtered, err := matchCommentFilters(db, &post, existingComment.UserID, existingComment.Content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking comment filters", "details": err.Error()})
+			return
+		}
+		if filtered && !existingComment.Hidden {
+			existingComment.Hidden, existingComment.HiddenReason = true, models.HiddenByFilter
+		}
 	}
 
-	// Save the updated comment to the database
-	if err := db.Save(&existingComment).Error; err != nil {
+	// Save the updated comment and re-index its mentions
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("LikeCount", "ReplyCount", "ParentID", "Depth", "Path", "PinnedAt", "AudioID", "AudioContent", "Audio").Save(&existingComment).Error; err != nil {
+			return err
+		}
+		source := mentionSource{AuthorID: existingComment.UserID, PostID: existingComment.PostID, CommentID: &existingComment.ID}
+		mentions, err := syncMentions(tx, source, existingComment.Content, !existingComment.Hidden)
+		if err != nil {
+			return err
+		}
+		existingComment.Mentions = mentions
+		if existingComment.Hidden && !wasHidden {
+			return adjustCommentCounters(tx, &existingComment, -1)
+		}
+		return nil
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating comment", "details": err.Error()})
 		return
 	}
@@ -128,7 +590,7 @@ func UpdateComment(c *gin.Context) {
 func DeleteComment(c *gin.Context) {
 	db := config.GetDB()
 	var comment models.Comment
-	commentID := c.Param("id")
+	commentID := c.Param("commentID")
 
 	// Find the comment by ID
 	if err := db.First(&comment, commentID).Error; err != nil {
@@ -136,11 +598,78 @@ func DeleteComment(c *gin.Context) {
 		return
 	}
 
-	// Delete the comment
-	if err := db.Delete(&comment).Error; err != nil {
+	// Commenters may delete their own comments, and post authors any comment on their posts
+	if comment.UserID != viewerID(c) {
+		var post models.Post
+		if err := db.Select("user_id").First(&post, comment.PostID).Error; err != nil || post.UserID != viewerID(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the commenter or the post's author can delete this comment"})
+			return
+		}
+	}
+
+	// Delete the comment with its replies, their mentions and voice recordings, and drop the counts together
+	var audioKeys []string
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var subtree []models.Comment
+		if err := tx.Select("id", "audio_id", "hidden").
+			Where("post_id = ? AND path LIKE ? ESCAPE '\\'", comment.PostID, utils.LikePrefix(comment.Path)).
+			Find(&subtree).Error; err != nil {
+			return err
+		}
+		keys, err := deleteComments(tx, subtree)
+		if err != nil {
+			return err
+		}
+		audioKeys = keys
+		if !comment.Hidden && comment.ParentID != nil {
+			if err := adjustCounter(tx, &models.Comment{}, *comment.ParentID, "reply_count", -1); err != nil {
+				return err
+			}
+		}
+		visible := 0
+		for _, doomed := range subtree {
+			if !doomed.Hidden {
+				visible++
+			}
+		}
+		return adjustCounter(tx, &models.Post{}, comment.PostID, "comment_count", -visible)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting comment", "details": err.Error()})
 		return
 	}
 
+	// Remove the recordings' files only once the rows are gone for good
+	deleteStoredObjects(audioKeys)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 }
+
+// deleteComments removes the given comments with their reactions, mentions,
+// notifications and voice recordings inside tx and returns the recordings'
+// storage keys, to delete once the transaction commits. Only the comments'
+// ID and AudioID need to be loaded.
+func deleteComments(tx *gorm.DB, comments []models.Comment) ([]string, error) {
+	if len(comments) == 0 {
+		return nil, nil
+	}
+	var ids, audioIDs []uint
+	for _, doomed := range comments {
+		ids = append(ids, doomed.ID)
+		if doomed.AudioID != nil {
+			audioIDs = append(audioIDs, *doomed.AudioID)
+		}
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&models.Mention{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&models.Notification{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&models.CommentLike{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+		return nil, err
+	}
+	return deleteAudioClips(tx, audioIDs)
+}
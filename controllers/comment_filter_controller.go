@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/utils"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Comment filter limits
+const (
+	maxCommentFilters      = 500
+	maxCommentFilterLength = 100 // Characters in one phrase
+)
+
+// GetCommentFilters lists the logged in user's filter phrases and whether the
+// built-in offensive word list is on
+func GetCommentFilters(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	var user models.User
+	if err := db.Select("id", "hide_offensive").First(&user, viewer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	filters := []models.CommentFilter{}
+	if err := db.Where("user_id = ?", viewer).Order("phrase").Find(&filters).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comment filters", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"filters": filters, "hide_offensive": user.HideOffensive})
+}
+
+// CreateCommentFilter adds a word or phrase to the logged in user's filter list.
+// It applies to comments written from now on.
+func CreateCommentFilter(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	var input struct {
+		Phrase string `json:"Phrase"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	// Store the phrase the way comments are matched against it
+	phrase := utils.NormalizeFilterPhrase(input.Phrase)
+	if phrase == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Phrase must contain at least one letter or digit"})
+		return
+	}
+	if utf8.RuneCountInString(phrase) > maxCommentFilterLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Phrase is too long"})
+		return
+	}
+
+	// Keep lists to a size that is cheap to match on every comment
+	var count int64
+	if err := db.Model(&models.CommentFilter{}).Where("user_id = ?", viewer).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment filter", "details": err.Error()})
+		return
+	}
+	if count >= maxCommentFilters {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment filter list is full"})
+		return
+	}
+
+	filter := models.CommentFilter{UserID: viewer, Phrase: phrase}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&filter)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment filter", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Phrase is already filtered"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Comment filter added successfully", "filter": filter})
+}
+
+// SetHideOffensive switches the built-in offensive word list on or off for
+// the logged in user's posts. It applies to comments written from now on.
+func SetHideOffensive(c *gin.Context) {
+	var input struct {
+		HideOffensive *bool `json:"HideOffensive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.HideOffensive == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "HideOffensive must be true or false"})
+		return
+	}
+
+	result := config.GetDB().Model(&models.User{}).Where("id = ?", viewerID(c)).Update("hide_offensive", *input.HideOffensive)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update offensive word filter", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hide_offensive": *input.HideOffensive})
+}
+
+// DeleteCommentFilter removes a phrase from the logged in user's filter list.
+// Comments it already hid stay hidden until unhidden from the review list.
+func DeleteCommentFilter(c *gin.Context) {
+	result := config.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), viewerID(c)).Delete(&models.CommentFilter{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment filter", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment filter not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment filter deleted successfully"})
+}
+
+// GetHiddenComments lists hidden comments and replies on the logged in user's
+// posts, newest first, so false positives can be unhidden. Pass reason=filter
+// to see only those hidden by filters.
+func GetHiddenComments(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	// Retrieve query parameters for infinite scroll
+	lastCommentID, err := strconv.Atoi(c.DefaultQuery("last_comment_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_comment_id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxCommentLimit {
+		limit = maxCommentLimit
+	}
+
+	query := db.Preload("User").Preload("Audio").
+		Joins("JOIN posts ON posts.id = comments.post_id").
+		Where("posts.user_id = ? AND comments.hidden = ?", viewer, true)
+	if reason := c.Query("reason"); reason != "" {
+		if reason != models.HiddenByAuthor && reason != models.HiddenByFilter {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be author or filter"})
+			return
+		}
+		query = query.Where("comments.hidden_reason = ?", reason)
+	}
+	if lastCommentID > 0 {
+		query = query.Where("comments.id < ?", lastCommentID)
+	}
+
+	comments := []models.Comment{}
+	if err := query.Order("comments.id DESC").Limit(limit).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hidden comments", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// matchCommentFilters reports whether content trips the post author's filters:
+// their own phrases, and the built-in list unless they opted out. Authors'
+// own comments are never filtered.
+func matchCommentFilters(db *gorm.DB, post *models.Post, commenterID uint, content string) (bool, error) {
+	if commenterID == post.UserID {
+		return false, nil
+	}
+
+	var owner models.User
+	if err := db.Select("id", "hide_offensive").First(&owner, post.UserID).Error; err != nil {
+		return false, err
+	}
+	if owner.HideOffensive {
+		if _, matched := utils.MatchFilter(content, utils.OffensiveWords); matched {
+			return true, nil
+		}
+	}
+
+	var phrases []string
+	if err := db.Model(&models.CommentFilter{}).Where("user_id = ?", post.UserID).Pluck("phrase", &phrases).Error; err != nil {
+		return false, err
+	}
+	_, matched := utils.MatchFilter(content, phrases)
+	return matched, nil
+}
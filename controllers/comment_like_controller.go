@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LikeComment likes a comment or reply as the logged in user, giving it the default reaction
+func LikeComment(c *gin.Context) {
+	db := config.GetDB()
+
+	comment, ok := findVisibleComment(c, db, c.Param("commentID"))
+	if !ok {
+		return
+	}
+
+	// Save the like and bump the comment's like count together; the unique
+	// index turns a second like into a no-op we can report as a conflict
+	created := false
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = commentReactions(comment.ID).add(tx, viewerID(c), models.DefaultReaction)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving like", "details": err.Error()})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": "Like already exists"})
+		return
+	}
+
+	comment.LikeCount++
+	c.JSON(http.StatusCreated, gin.H{"message": "Like created successfully", "like_count": comment.LikeCount})
+}
+
+// UnlikeComment removes the logged in user's like, or other reaction, from a comment or reply
+func UnlikeComment(c *gin.Context) {
+	db := config.GetDB()
+
+	var comment models.Comment
+	if err := db.First(&comment, c.Param("commentID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+
+	// Delete the like and drop the comment's like count together
+	deleted := false
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = commentReactions(comment.ID).remove(tx, viewerID(c))
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete like", "details": err.Error()})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Like not found"})
+		return
+	}
+
+	if comment.LikeCount > 0 {
+		comment.LikeCount--
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Like deleted successfully", "like_count": comment.LikeCount})
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errTooManyPinned aborts a pin that would exceed models.MaxPinnedComments
+var errTooManyPinned = errors.New("too many pinned comments")
+
+// PinComment pins a top-level comment to the top of the post's comments. Only
+// the post's author may pin, and at most models.MaxPinnedComments at a time.
+func PinComment(c *gin.Context) {
+	db := config.GetDB()
+
+	comment, ok := findModeratedComment(c, db)
+	if !ok {
+		return
+	}
+	if comment.ParentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only top-level comments can be pinned"})
+		return
+	}
+	if comment.Hidden {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Hidden comments cannot be pinned"})
+		return
+	}
+	if comment.PinnedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Comment pinned successfully", "comment": comment})
+		return
+	}
+
+	// Lock the post while counting and pinning, so concurrent pins on it wait
+	// their turn and see each other's pins; the transaction alone would let
+	// two of them count the same pins
+	now := time.Now()
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var post models.Post
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&post, comment.PostID).Error; err != nil {
+			return err
+		}
+
+		var pinned int64
+		if err := tx.Model(&models.Comment{}).Where("post_id = ? AND pinned_at IS NOT NULL", comment.PostID).Count(&pinned).Error; err != nil {
+			return err
+		}
+		if pinned >= models.MaxPinnedComments {
+			return errTooManyPinned
+		}
+		return tx.Model(comment).Update("pinned_at", now).Error
+	}); err == errTooManyPinned {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A post can have at most %d pinned comments; unpin one first", models.MaxPinnedComments)})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin comment", "details": err.Error()})
+		return
+	}
+
+	comment.PinnedAt = &now
+	c.JSON(http.StatusOK, gin.H{"message": "Comment pinned successfully", "comment": comment})
+}
+
+// UnpinComment returns a pinned comment to its usual place
+func UnpinComment(c *gin.Context) {
+	db := config.GetDB()
+
+	comment, ok := findModeratedComment(c, db)
+	if !ok {
+		return
+	}
+
+	if err := db.Model(comment).Update("pinned_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin comment", "details": err.Error()})
+		return
+	}
+
+	comment.PinnedAt = nil
+	c.JSON(http.StatusOK, gin.H{"message": "Comment unpinned successfully", "comment": comment})
+}
+
+// HideComment hides a comment or reply from everyone but its author and the
+// post's author. Hidden comments are also unpinned.
+func HideComment(c *gin.Context) {
+	setCommentHidden(c, true)
+}
+
+// UnhideComment shows a hidden comment to everyone again, including comments
+// hidden by the author's filters
+func UnhideComment(c *gin.Context) {
+	setCommentHidden(c, false)
+}
+
+// setCommentHidden updates the hidden flag for HideComment and UnhideComment
+func setCommentHidden(c *gin.Context, hidden bool) {
+	db := config.GetDB()
+
+	comment, ok := findModeratedComment(c, db)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{"hidden": hidden, "hidden_reason": ""}
+	if hidden {
+		updates["hidden_reason"] = models.HiddenByAuthor
+		updates["pinned_at"] = nil
+	}
+	// Hidden comments are left out of the post's and parent's counts
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(comment).Updates(updates).Error; err != nil {
+			return err
+		}
+		if comment.Hidden == hidden {
+			return nil
+		}
+		delta := 1
+		if hidden {
+			delta = -1
+		}
+		return adjustCommentCounters(tx, comment, delta)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment", "details": err.Error()})
+		return
+	}
+
+	comment.Hidden, comment.HiddenReason = hidden, updates["hidden_reason"].(string)
+	if hidden {
+		comment.PinnedAt = nil
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully", "comment": comment})
+}
+
+// findModeratedComment loads the comment in the :commentID param, writing a
+// 404 when it does not exist and a 403 unless the viewer wrote the post
+func findModeratedComment(c *gin.Context, db *gorm.DB) (*models.Comment, bool) {
+	var comment models.Comment
+	if err := db.First(&comment, c.Param("commentID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return nil, false
+	}
+
+	var post models.Post
+	if err := db.Select("user_id").First(&post, comment.PostID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+	if post.UserID != viewerID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the post's author can moderate its comments"})
+		return nil, false
+	}
+	return &comment, true
+}
+
+// canComment reports whether a user may comment on post under its comment
+// policy, with the reason to show when they may not. Authors can always
+// comment on their own posts unless comments are off.
+func canComment(db *gorm.DB, post *models.Post, userID uint) (bool, string, error) {
+	switch post.CommentPolicy {
+	case models.CommentPolicyOff:
+		return false, "Comments are turned off for this post", nil
+	case models.CommentPolicyEveryone:
+		return true, "", nil
+	}
+	if userID == post.UserID {
+		return true, "", nil
+	}
+
+	if post.CommentPolicy == models.CommentPolicyFollowers {
+		following, err := isFollowing(db, userID, post.UserID)
+		return following, "Only followers can comment on this post", err
+	}
+	followed, err := isFollowing(db, post.UserID, userID)
+	return followed, "Only accounts the author follows can comment on this post", err
+}
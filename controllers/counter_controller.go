@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"log"
+	"pixi/config"
+	"pixi/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// adjustCounter atomically adds delta to a counter column on a single row.
+// Decrements never take a counter below zero.
+func adjustCounter(tx *gorm.DB, model interface{}, id uint, column string, delta int) error {
+	query := tx.Model(model).Where("id = ?", id)
+	if delta < 0 {
+		query = query.Where(column+" >= ?", -delta)
+	}
+	return query.UpdateColumn(column, gorm.Expr(column+" + ?", delta)).Error
+}
+
+// adjustCommentCounters adds delta to the comment count of the comment's post
+// and the reply count of its parent. Hidden comments are left out of both,
+// so other viewers never see counts for comments they cannot load.
+func adjustCommentCounters(tx *gorm.DB, comment *models.Comment, delta int) error {
+	if comment.ParentID != nil {
+		if err := adjustCounter(tx, &models.Comment{}, *comment.ParentID, "reply_count", delta); err != nil {
+			return err
+		}
+	}
+	return adjustCounter(tx, &models.Post{}, comment.PostID, "comment_count", delta)
+}
+
+// counterReconciliations recompute every denormalized counter from its source table
+var counterReconciliations = []struct {
+	Name  string
+	Query string
+}{
+	{"posts.like_count", "UPDATE posts SET like_count = (SELECT COUNT(*) FROM likes WHERE likes.post_id = posts.id)"},
+	{"posts.comment_count", "UPDATE posts SET comment_count = (SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.hidden = false)"},
+	{"posts.save_count", "UPDATE posts SET save_count = (SELECT COUNT(*) FROM saves WHERE saves.post_id = posts.id)"},
+	{"users.follower_count", "UPDATE users SET follower_count = (SELECT COUNT(*) FROM follows WHERE follows.following_id = users.id AND follows.status = 'accepted')"},
+	{"users.following_count", "UPDATE users SET following_count = (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id AND follows.status = 'accepted')"},
+	{"comments.like_count", "UPDATE comments SET like_count = (SELECT COUNT(*) FROM comment_likes WHERE comment_likes.comment_id = comments.id)"},
+	{"comments.reply_count", "UPDATE comments SET reply_count = (SELECT COUNT(*) FROM comments AS children WHERE children.parent_id = comments.id AND children.hidden = false)"},
+	{"hashtags.post_count", "UPDATE hashtags SET post_count = (SELECT COUNT(*) FROM post_hashtags JOIN posts ON posts.id = post_hashtags.post_id WHERE post_hashtags.hashtag_id = hashtags.id AND posts.status = 'published')"},
+	{"users.post_count", "UPDATE users SET post_count = (SELECT COUNT(*) FROM posts WHERE posts.user_id = users.id AND posts.status = 'published')"},
+}
+
+// Cron job or background task to repair drift in the denormalized counters
+func ReconcileCounters() {
+	db := config.GetDB()
+
+	log.Println("Running ReconcileCounters at:", time.Now().UTC())
+
+	for _, reconciliation := range counterReconciliations {
+		result := db.Exec(reconciliation.Query)
+		if result.Error != nil {
+			log.Println("Error reconciling", reconciliation.Name, ":", result.Error)
+			continue
+		}
+		log.Println("Reconciled", reconciliation.Name, "(rows:", result.RowsAffected, ")")
+	}
+}
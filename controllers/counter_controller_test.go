@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"pixi/models"
+	"testing"
+)
+
+func TestCounterReconciliationsSkipHiddenComments(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	mustCreate(t, db, author)
+	post := &models.Post{Caption: "post", ImageURL: "post", UserID: author.ID, Status: "published"}
+	mustCreate(t, db, post)
+	comment := &models.Comment{Author: "author", Content: "Top", PostID: post.ID, UserID: author.ID}
+	mustCreate(t, db, comment)
+	mustCreate(t, db,
+		&models.Comment{Author: "author", Content: "Shown", PostID: post.ID, UserID: author.ID, ParentID: &comment.ID, Depth: 1},
+		&models.Comment{Author: "author", Content: "Filtered", PostID: post.ID, UserID: author.ID, ParentID: &comment.ID, Depth: 1,
+			Hidden: true, HiddenReason: models.HiddenByFilter})
+
+	for _, reconciliation := range counterReconciliations {
+		if err := db.Exec(reconciliation.Query).Error; err != nil {
+			t.Fatalf("reconciling %s: %v", reconciliation.Name, err)
+		}
+	}
+
+	var reconciledPost models.Post
+	if err := db.First(&reconciledPost, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reconciledPost.CommentCount != 2 {
+		t.Errorf("CommentCount = %d, want 2", reconciledPost.CommentCount)
+	}
+	var reconciledComment models.Comment
+	if err := db.First(&reconciledComment, comment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reconciledComment.ReplyCount != 1 {
+		t.Errorf("ReplyCount = %d, want 1", reconciledComment.ReplyCount)
+	}
+}
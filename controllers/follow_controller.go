@@ -10,15 +10,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// GetFollowingsByUserID lists the accounts a user follows
 func GetFollowingsByUserID(c *gin.Context) {
 	db := config.GetDB()
 	var followers []models.Follow
 	userID := c.Param("id") // Get the userID from the URL parameter
 
+	// A private account's follow lists are only visible to its approved followers
+	if !canViewFollowLists(c, db, userID) {
+		return
+	}
+
 	// Query the Follow table and filter by FolloweeID
-	if err := db.Preload("Following").Omit("Follower").Where("follower_id = ?", userID).Find(&followers).Error; err != nil {
+	if err := db.Preload("Following").Omit("Follower").Scopes(notBlocked(viewerID(c), "follows.following_id")).
+		Where("follower_id = ? AND status = ?", userID, models.FollowStatusAccepted).Find(&followers).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Follow data not found"})
 		return
 	}
@@ -26,12 +34,19 @@ func GetFollowingsByUserID(c *gin.Context) {
 	c.JSON(http.StatusOK, followers)
 }
 
+// GetFollowersByUserID lists the accounts following a user
 func GetFollowersByUserID(c *gin.Context) {
 	db := config.GetDB()
 	var followings []models.Follow
 	userID := c.Param("id")
 
-	if err := db.Preload("Follower").Omit("Following").Where("following_id = ?", userID).Find(&followings).Error; err != nil {
+	// A private account's follow lists are only visible to its approved followers
+	if !canViewFollowLists(c, db, userID) {
+		return
+	}
+
+	if err := db.Preload("Follower").Omit("Following").Scopes(notBlocked(viewerID(c), "follows.follower_id")).
+		Where("following_id = ? AND status = ?", userID, models.FollowStatusAccepted).Find(&followings).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Follow data not found"})
 		return
 	}
@@ -39,6 +54,38 @@ func GetFollowersByUserID(c *gin.Context) {
 	c.JSON(http.StatusOK, followings)
 }
 
+// canViewFollowLists checks the viewer may see a user's follow lists, writing
+// the error response when they may not
+func canViewFollowLists(c *gin.Context, db *gorm.DB, userID string) bool {
+	var owner models.User
+	if err := db.First(&owner, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return false
+	}
+
+	// Blocked profiles look the same as missing ones
+	blocked, err := isBlocked(db, owner.ID, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking block", "details": err.Error()})
+		return false
+	}
+	if blocked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return false
+	}
+
+	visible, err := canViewUserContent(db, &owner, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking account visibility", "details": err.Error()})
+		return false
+	}
+	if !visible {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account is private"})
+		return false
+	}
+	return true
+}
+
 func CreateAFollow(c *gin.Context) {
 	var newFollow models.Follow
 
@@ -49,15 +96,15 @@ func CreateAFollow(c *gin.Context) {
 		return
 	}
 
-	// Assignes the user id from the context as the FollowerID
-	newFollow.FollowerID = followerID.(uint)
-
 	// Binds the request body to newFollow object
 	if err := c.ShouldBindJSON(&newFollow); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
+	// The follower is always the logged in user, never taken from the client
+	newFollow.FollowerID = followerID.(uint)
+
 	// Validate required fields
 	if newFollow.FollowingID == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Following ID is required"})
@@ -87,34 +134,53 @@ func CreateAFollow(c *gin.Context) {
 		return
 	}
 
-	// Check if the relationship already exists
-	var existingFollow models.Follow
-	err := db.Where("follower_id = ? AND following_id = ?", newFollow.FollowerID, newFollow.FollowingID).First(&existingFollow).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		// Unexpected error while checking the relationship
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking follow relationship", "details": err.Error()})
+	// Blocks in either direction prevent new follows
+	blocked, err := isBlocked(db, newFollow.FollowerID, following.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking block", "details": err.Error()})
 		return
 	}
-
-	// If relationship already exists, return a conflict
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Follow relationship already exists"})
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot follow this user"})
 		return
 	}
 
-	// No existing relationship found, so we proceed to create the new follow relationship
-	if err := db.Create(&newFollow).Error; err != nil {
+	// Create the relationship and bump both users' counts together
+	created := false
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = followUser(tx, &newFollow, &following)
+		return err
+	}); err != nil {
 		log.Printf("Failed to create follow relationship: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create follower", "details": err.Error()})
 		return
 	}
 
+	// If relationship already exists, return a conflict
+	if !created {
+		if newFollow.Status == models.FollowStatusPending {
+			c.JSON(http.StatusConflict, gin.H{"error": "Follow request already sent"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Follow relationship already exists"})
+		return
+	}
+
 	var createdFollow models.Follow
 	if err := db.Preload("Following").First(&createdFollow, newFollow.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving follow with associations", "details": err.Error()})
 		return
 	}
 
+	// A pending request is accepted for processing rather than created
+	if createdFollow.Status == models.FollowStatusPending {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "Follow request sent",
+			"follower": createdFollow})
+		return
+	}
+
 	// Respond with the created follower
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Follow created successfully",
@@ -154,11 +220,270 @@ func DeleteFollower(c *gin.Context) {
 		return
 	}
 
-	// Delete the follower relationship from the database
-	if err := db.Delete(&follower).Error; err != nil {
+	// Delete the follower relationship and drop both users' counts together
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return removeFollow(tx, &follower)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete follower relationship", "details": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Follower relationship deleted successfully"})
 }
+
+// followUser creates the follow described by follow, or a pending request when
+// the followed account is private. The status is always decided here, never
+// taken from the client. An existing row is loaded into follow and left
+// untouched, so repeating the call is harmless; created reports whether a new
+// row was written. It must run inside a transaction.
+func followUser(tx *gorm.DB, follow *models.Follow, following *models.User) (created bool, err error) {
+	*follow = models.Follow{FollowerID: follow.FollowerID, FollowingID: following.ID, Status: models.FollowStatusAccepted}
+	if following.IsPrivate {
+		follow.Status = models.FollowStatusPending
+	}
+
+	// The unique (follower_id, following_id) index turns a concurrent duplicate into a no-op
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(follow)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, tx.Where("follower_id = ? AND following_id = ?", follow.FollowerID, follow.FollowingID).First(follow).Error
+	}
+
+	if follow.Status != models.FollowStatusAccepted {
+		return true, nil
+	}
+	if err := adjustCounter(tx, &models.User{}, follow.FollowerID, "following_count", 1); err != nil {
+		return false, err
+	}
+	return true, adjustCounter(tx, &models.User{}, follow.FollowingID, "follower_count", 1)
+}
+
+// removeFollow deletes a follow or pending request and, for accepted follows,
+// drops both users' counters. A row already deleted by a concurrent request
+// leaves the counters alone. It must run inside a transaction.
+func removeFollow(tx *gorm.DB, follow *models.Follow) error {
+	result := tx.Delete(follow)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 || follow.Status != models.FollowStatusAccepted {
+		return nil
+	}
+	if err := adjustCounter(tx, &models.User{}, follow.FollowerID, "following_count", -1); err != nil {
+		return err
+	}
+	return adjustCounter(tx, &models.User{}, follow.FollowingID, "follower_count", -1)
+}
+
+// maxBulkFollowBatch caps how many users one bulk follow request may touch
+const maxBulkFollowBatch = 200
+
+// Per-user outcomes reported by BulkFollow
+const (
+	bulkFollowFollowed         = "followed"
+	bulkFollowRequested        = "requested"
+	bulkFollowAlreadyFollowing = "already_following"
+	bulkFollowAlreadyRequested = "already_requested"
+	bulkFollowUnfollowed       = "unfollowed"
+	bulkFollowNotFollowing     = "not_following"
+	bulkFollowNotFound         = "not_found"
+	bulkFollowBlocked          = "blocked"
+	bulkFollowSelf             = "self"
+)
+
+// bulkFollowResult is the outcome for one user in a bulk follow request
+type bulkFollowResult struct {
+	UserID uint
+	Result string
+}
+
+// GetFollowByUserID returns the logged in user's follow or pending request to another user
+func GetFollowByUserID(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	db := config.GetDB()
+	var follow models.Follow
+	err := db.Preload("Following").Where("follower_id = ? AND following_id = ?", viewerID(c), userID).First(&follow).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Follow relationship not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, follow)
+}
+
+// UnfollowUser removes the logged in user's follow or pending request to another user.
+// Unfollowing someone who is not followed succeeds without changes.
+func UnfollowUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	removed, err := removeFollowBetween(config.GetDB(), viewerID(c), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unfollowed successfully", "removed": removed})
+}
+
+// RemoveFollower removes another user's follow or pending request to the logged in user.
+// Removing someone who does not follow succeeds without changes.
+func RemoveFollower(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	removed, err := removeFollowBetween(config.GetDB(), userID, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove follower", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Follower removed successfully", "removed": removed})
+}
+
+// BulkFollow follows and unfollows many users at once, e.g. when importing a list.
+// Every entry is idempotent and the whole batch is applied in one transaction.
+func BulkFollow(c *gin.Context) {
+	var input struct {
+		Follow   []uint `json:"Follow"`
+		Unfollow []uint `json:"Unfollow"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if len(input.Follow)+len(input.Unfollow) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Follow or Unfollow must list at least one user ID"})
+		return
+	}
+	if len(input.Follow)+len(input.Unfollow) > maxBulkFollowBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many user IDs", "max": maxBulkFollowBatch})
+		return
+	}
+
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	// Load every account to follow, and everyone on either side of a block with the viewer, up front
+	var targets []models.User
+	if len(input.Follow) > 0 {
+		if err := db.Where("id IN ?", input.Follow).Find(&targets).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load users", "details": err.Error()})
+			return
+		}
+	}
+	targetsByID := make(map[uint]*models.User, len(targets))
+	for i := range targets {
+		targetsByID[targets[i].ID] = &targets[i]
+	}
+
+	var blocks []models.Block
+	if err := db.Where("blocker_id = ? OR blocked_id = ?", viewer, viewer).Find(&blocks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking blocks", "details": err.Error()})
+		return
+	}
+	blockedIDs := make(map[uint]bool, len(blocks))
+	for _, block := range blocks {
+		if block.BlockerID == viewer {
+			blockedIDs[block.BlockedID] = true
+		} else {
+			blockedIDs[block.BlockerID] = true
+		}
+	}
+
+	results := []bulkFollowResult{}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		results = results[:0]
+
+		for _, userID := range input.Follow {
+			result := bulkFollowResult{UserID: userID}
+			following := targetsByID[userID]
+			switch {
+			case userID == viewer:
+				result.Result = bulkFollowSelf
+			case following == nil:
+				result.Result = bulkFollowNotFound
+			case blockedIDs[userID]:
+				result.Result = bulkFollowBlocked
+			default:
+				follow := models.Follow{FollowerID: viewer}
+				created, err := followUser(tx, &follow, following)
+				if err != nil {
+					return err
+				}
+				switch {
+				case created && follow.Status == models.FollowStatusPending:
+					result.Result = bulkFollowRequested
+				case created:
+					result.Result = bulkFollowFollowed
+				case follow.Status == models.FollowStatusPending:
+					result.Result = bulkFollowAlreadyRequested
+				default:
+					result.Result = bulkFollowAlreadyFollowing
+				}
+			}
+			results = append(results, result)
+		}
+
+		for _, userID := range input.Unfollow {
+			removed, err := removeFollowBetween(tx, viewer, userID)
+			if err != nil {
+				return err
+			}
+			result := bulkFollowResult{UserID: userID, Result: bulkFollowNotFollowing}
+			if removed {
+				result.Result = bulkFollowUnfollowed
+			}
+			results = append(results, result)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bulk follow", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// removeFollowBetween deletes the follow or pending request from follower to
+// following if there is one, reporting whether anything was removed
+func removeFollowBetween(db *gorm.DB, followerID, followingID uint) (bool, error) {
+	removed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var follow models.Follow
+		err := tx.Where("follower_id = ? AND following_id = ?", followerID, followingID).First(&follow).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		removed = true
+		return removeFollow(tx, &follow)
+	})
+	return removed, err
+}
+
+// userIDParam parses the :userID route parameter, writing a 400 response when it is invalid
+func userIDParam(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+	if err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(userID), true
+}
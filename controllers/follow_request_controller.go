@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetFollowRequests lists the pending follow requests sent to the logged in user
+func GetFollowRequests(c *gin.Context) {
+	db := config.GetDB()
+	var requests []models.Follow
+
+	if err := db.Preload("Follower", publicUserColumns).Omit("Following").
+		Where("following_id = ? AND status = ?", viewerID(c), models.FollowStatusPending).
+		Order("created_at DESC").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve follow requests", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetSentFollowRequests lists the pending follow requests the logged in user has sent
+func GetSentFollowRequests(c *gin.Context) {
+	db := config.GetDB()
+	var requests []models.Follow
+
+	if err := db.Preload("Following", publicUserColumns).Omit("Follower").
+		Where("follower_id = ? AND status = ?", viewerID(c), models.FollowStatusPending).
+		Order("created_at DESC").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sent follow requests", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// ApproveFollowRequest turns a pending follow request into an accepted follow
+func ApproveFollowRequest(c *gin.Context) {
+	db := config.GetDB()
+
+	// Find the pending request addressed to the logged in user
+	var request models.Follow
+	if !findFollowRequest(c, db, &request) {
+		return
+	}
+
+	// Accept the request and bump both users' counts together
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return acceptFollow(tx, &request)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve follow request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Follow request approved", "follower": request})
+}
+
+// RejectFollowRequest deletes a pending follow request addressed to the logged in user
+func RejectFollowRequest(c *gin.Context) {
+	db := config.GetDB()
+
+	// Find the pending request addressed to the logged in user
+	var request models.Follow
+	if !findFollowRequest(c, db, &request) {
+		return
+	}
+
+	if err := db.Delete(&request).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject follow request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Follow request rejected"})
+}
+
+// findFollowRequest loads the pending request named by the :id param, writing
+// the error response when it does not belong to the logged in user
+func findFollowRequest(c *gin.Context, db *gorm.DB, request *models.Follow) bool {
+	err := db.Where("id = ? AND following_id = ? AND status = ?", c.Param("id"), viewerID(c), models.FollowStatusPending).
+		First(request).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Follow request not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error", "details": err.Error()})
+		return false
+	}
+	return true
+}
+
+// acceptFollow marks a pending follow as accepted and updates both users' counters.
+// It must run inside a transaction.
+func acceptFollow(tx *gorm.DB, follow *models.Follow) error {
+	result := tx.Model(follow).Where("status = ?", models.FollowStatusPending).Update("status", models.FollowStatusAccepted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil // Already accepted by a concurrent request
+	}
+	if err := adjustCounter(tx, &models.User{}, follow.FollowerID, "following_count", 1); err != nil {
+		return err
+	}
+	return adjustCounter(tx, &models.User{}, follow.FollowingID, "follower_count", 1)
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxHashtagPostsLimit caps how many posts one page of a hashtag returns
+const maxHashtagPostsLimit = 50
+
+// GetHashtag returns a hashtag with its post count and whether the logged in user follows it
+func GetHashtag(c *gin.Context) {
+	db := config.GetDB()
+
+	hashtag, ok := findHashtag(c, db)
+	if !ok {
+		return
+	}
+
+	// Check whether the viewer follows the tag
+	var following int64
+	if viewer := viewerID(c); viewer != 0 {
+		if err := db.Model(&models.HashtagFollow{}).Where("user_id = ? AND hashtag_id = ?", viewer, hashtag.ID).Count(&following).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hashtag", "details": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hashtag": hashtag, "following": following > 0})
+}
+
+// GetHashtagPosts lists the published posts tagged with a hashtag that the viewer may see
+func GetHashtagPosts(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	hashtag, ok := findHashtag(c, db)
+	if !ok {
+		return
+	}
+
+	// Retrieve query parameters for infinite scroll
+	lastPostID, err := strconv.Atoi(c.DefaultQuery("last_post_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_post_id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxHashtagPostsLimit {
+		limit = maxHashtagPostsLimit
+	}
+
+	// Fetch tagged posts after the last loaded post
+	var posts []models.Post
+	if err := db.Preload("User").Preload("Hashtags").Preload("Mentions").Scopes(withPostItems).
+		Scopes(visiblePosts(viewer), notMuted(viewer, "posts.user_id", "mute_posts")).
+		Joins("JOIN post_hashtags ON post_hashtags.post_id = posts.id").
+		Where("post_hashtags.hashtag_id = ? AND posts.status = ? AND posts.id > ?", hashtag.ID, "published", lastPostID).
+		Order("posts.id").Limit(limit).Find(&posts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts", "details": err.Error()})
+		return
+	}
+
+	// Add reaction counts, hiding them on posts whose author asked for it
+	if err := attachPostReactions(db, viewer, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve posts", "details": err.Error()})
+		return
+	}
+	for i := range posts {
+		applyLikeVisibility(&posts[i], viewer)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hashtag": hashtag, "posts": posts})
+}
+
+// FollowHashtag subscribes the logged in user to a hashtag
+func FollowHashtag(c *gin.Context) {
+	db := config.GetDB()
+
+	name := utils.NormalizeHashtag(c.Param("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hashtag"})
+		return
+	}
+
+	// Tags may be followed before anyone has posted with them
+	hashtag := models.Hashtag{Name: name}
+	if err := db.Where(models.Hashtag{Name: name}).FirstOrCreate(&hashtag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow hashtag", "details": err.Error()})
+		return
+	}
+
+	// Following twice is harmless
+	follow := models.HashtagFollow{UserID: viewerID(c), HashtagID: hashtag.ID}
+	if err := db.Where(follow).FirstOrCreate(&follow).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow hashtag", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hashtag followed successfully", "hashtag": hashtag})
+}
+
+// UnfollowHashtag unsubscribes the logged in user from a hashtag
+func UnfollowHashtag(c *gin.Context) {
+	db := config.GetDB()
+
+	hashtag, ok := findHashtag(c, db)
+	if !ok {
+		return
+	}
+
+	result := db.Where("user_id = ? AND hashtag_id = ?", viewerID(c), hashtag.ID).Delete(&models.HashtagFollow{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow hashtag", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You do not follow this hashtag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hashtag unfollowed successfully"})
+}
+
+// GetFollowedHashtags lists the hashtags the logged in user follows
+func GetFollowedHashtags(c *gin.Context) {
+	var hashtags []models.Hashtag
+	if err := config.GetDB().
+		Joins("JOIN hashtag_follows ON hashtag_follows.hashtag_id = hashtags.id").
+		Where("hashtag_follows.user_id = ?", viewerID(c)).
+		Order("hashtags.name").Find(&hashtags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve hashtags", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hashtags": hashtags})
+}
+
+// findHashtag loads the hashtag named in the :name param, writing a 404 when it does not exist
+func findHashtag(c *gin.Context, db *gorm.DB) (*models.Hashtag, bool) {
+	name := utils.NormalizeHashtag(c.Param("name"))
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hashtag"})
+		return nil, false
+	}
+
+	var hashtag models.Hashtag
+	if err := db.Where("name = ?", name).First(&hashtag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hashtag not found"})
+		return nil, false
+	}
+	return &hashtag, true
+}
+
+// syncPostHashtags indexes the hashtags in a post's caption and description,
+// replacing whatever the post was tagged with before
+func syncPostHashtags(tx *gorm.DB, post *models.Post) error {
+	return setPostHashtags(tx, post, utils.ExtractHashtags(post.Caption, post.Description))
+}
+
+// setPostHashtags tags a post with exactly the given hashtag names. Tag post
+// counts only include published posts, so they are adjusted only for those.
+func setPostHashtags(tx *gorm.DB, post *models.Post, names []string) error {
+	// Create any hashtags seen for the first time
+	hashtags := []models.Hashtag{}
+	if len(names) > 0 {
+		newTags := make([]models.Hashtag, len(names))
+		for i, name := range names {
+			newTags[i] = models.Hashtag{Name: name}
+		}
+		if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&newTags).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("name IN ?", names).Find(&hashtags).Error; err != nil {
+			return err
+		}
+	}
+
+	// Work out which tags were added and removed
+	oldIDs, err := postHashtagIDs(tx, post.ID)
+	if err != nil {
+		return err
+	}
+	kept := map[uint]bool{}
+	for _, id := range oldIDs {
+		kept[id] = true
+	}
+	added, wanted := []uint{}, map[uint]bool{}
+	for _, hashtag := range hashtags {
+		wanted[hashtag.ID] = true
+		if !kept[hashtag.ID] {
+			added = append(added, hashtag.ID)
+		}
+	}
+	removed := []uint{}
+	for _, id := range oldIDs {
+		if !wanted[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	// Update the join table
+	if len(removed) > 0 {
+		if err := tx.Table("post_hashtags").Where("post_id = ? AND hashtag_id IN ?", post.ID, removed).Delete(nil).Error; err != nil {
+			return err
+		}
+	}
+	if len(added) > 0 {
+		rows := make([]map[string]interface{}, len(added))
+		for i, id := range added {
+			rows[i] = map[string]interface{}{"post_id": post.ID, "hashtag_id": id}
+		}
+		if err := tx.Table("post_hashtags").Create(rows).Error; err != nil {
+			return err
+		}
+	}
+
+	post.Hashtags = hashtags
+	if post.Status != "published" {
+		return nil
+	}
+	for i := range post.Hashtags {
+		if !kept[post.Hashtags[i].ID] {
+			post.Hashtags[i].PostCount++
+		}
+	}
+	if err := adjustHashtagCounts(tx, added, 1); err != nil {
+		return err
+	}
+	if err := recordHashtagUsage(tx, added, time.Now()); err != nil {
+		return err
+	}
+	return adjustHashtagCounts(tx, removed, -1)
+}
+
+// adjustHashtagCounts adds delta to the post count of every hashtag in ids
+func adjustHashtagCounts(tx *gorm.DB, ids []uint, delta int) error {
+	for _, id := range ids {
+		if err := adjustCounter(tx, &models.Hashtag{}, id, "post_count", delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postHashtagIDs returns the IDs of the hashtags a post is tagged with
+func postHashtagIDs(tx *gorm.DB, postID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Table("post_hashtags").Where("post_id = ?", postID).Pluck("hashtag_id", &ids).Error
+	return ids, err
+}
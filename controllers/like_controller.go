@@ -4,8 +4,10 @@ import (
 	"net/http"
 	"pixi/config"
 	"pixi/models"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreateLike handles the creation of a new like
@@ -25,8 +27,9 @@ func CreateLike(c *gin.Context) {
 		return
 	}
 
-	// Assign the UserID from the context to the new like
+	// Assign the UserID from the context to the new like; a like is always the default reaction
 	newLike.UserID = userID.(uint)
+	newLike.Emoji = models.DefaultReaction
 
 	// Validate required fields
 	if newLike.UserID == 0 || newLike.PostID == 0 {
@@ -39,21 +42,26 @@ func CreateLike(c *gin.Context) {
 
 	// Ensure the post exists
 	var post models.Post
-	if err := db.First(&post, newLike.PostID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	if !findVisiblePost(c, db, newLike.PostID, &post) {
 		return
 	}
 
-	// Prevent duplicate likes
-	var existingLike models.Like
-	if err := db.Where("user_id = ? AND post_id = ?", newLike.UserID, newLike.PostID).First(&existingLike).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Like already exists"})
+	// Save the like and bump the post's like count together; the unique
+	// index turns a second like into a no-op we can report as a conflict
+	created := false
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = postReactions(newLike.PostID).add(tx, newLike.UserID, models.DefaultReaction)
+		if err != nil || !created {
+			return err
+		}
+		return tx.Where("user_id = ? AND post_id = ?", newLike.UserID, newLike.PostID).First(&newLike).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving like", "details": err.Error()})
 		return
 	}
-
-	// Save the new like to the database
-	if err := db.Create(&newLike).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving like", "details": err.Error()})
+	if !created {
+		c.JSON(http.StatusConflict, gin.H{"error": "Like already exists"})
 		return
 	}
 
@@ -61,32 +69,42 @@ func CreateLike(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Like created successfully", "like": newLike})
 }
 
-// DeleteLike handles the deletion of a like
+// DeleteLike removes the logged in user's like, or other reaction, from a
+// post. The user ID in the URL is kept for older clients and must be the
+// logged in user's own.
 func DeleteLike(c *gin.Context) {
-	// Get PostID and UserID from query parameters or request body
-	postID := c.Param("postID")
-	userID := c.Param("userID")
-
-	if postID == "" || userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PostID and UserID are required"})
+	postID, err := strconv.ParseUint(c.Param("postID"), 10, 64)
+	if err != nil || postID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+	if err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Get the DB connection
-	db := config.GetDB()
-
-	// Find the like by PostID and UserID
-	var like models.Like
-	if err := db.Where("post_id = ? AND user_id = ?", postID, userID).First(&like).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Like not found"})
+	// Users may only remove their own likes
+	viewer := viewerID(c)
+	if uint(userID) != viewer {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only remove your own likes"})
 		return
 	}
 
-	// Delete the like
-	if err := db.Delete(&like).Error; err != nil {
+	// Delete the like and drop the post's like count together
+	deleted := false
+	if err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = postReactions(uint(postID)).remove(tx, viewer)
+		return err
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete like", "details": err.Error()})
 		return
 	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Like not found"})
+		return
+	}
 
 	// Respond with success
 	c.JSON(http.StatusOK, gin.H{"message": "Like deleted successfully"})
@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/storage"
+	"pixi/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Media upload limits, unless MAX_MEDIA_BYTES, MAX_VIDEO_BYTES or
+// MEDIA_ORPHAN_TTL say otherwise
+const (
+	defaultMaxMediaBytes  = 20 << 20
+	defaultMaxVideoBytes  = 200 << 20
+	defaultMediaOrphanTTL = 24 * time.Hour   // Media not used in a post within this is removed
+	mediaUploadExpiry     = 15 * time.Minute // How long a direct upload URL stays valid
+)
+
+// mediaTypes maps the image and video types posts accept to the extension
+// uploads are stored with until they are processed
+var mediaTypes = map[string]string{
+	"image/jpeg":             ".jpg",
+	"image/png":              ".png",
+	"image/gif":              ".gif",
+	utils.VideoTypeMP4:       ".mp4",
+	utils.VideoTypeQuickTime: ".mov",
+	utils.VideoTypeWebM:      ".webm",
+}
+
+// maxMediaBytes is the largest image accepted for a post
+func maxMediaBytes() int64 {
+	return int64(envInt("MAX_MEDIA_BYTES", defaultMaxMediaBytes))
+}
+
+// maxVideoBytes is the largest video accepted for a post
+func maxVideoBytes() int64 {
+	return int64(envInt("MAX_VIDEO_BYTES", defaultMaxVideoBytes))
+}
+
+// mediaKind tells videos from images by their content type
+func mediaKind(contentType string) string {
+	if strings.HasPrefix(contentType, "video/") {
+		return models.MediaKindVideo
+	}
+	return models.MediaKindImage
+}
+
+// mediaKeyPrefix keeps each user's uploads together in storage
+func mediaKeyPrefix(userID uint) string {
+	return "media/" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// UploadMedia stores an image or video sent as the multipart field "file".
+// Images are processed right away, see storeImageRenditions; videos are
+// stored as they are and queued for transcoding, see ProcessVideoJobs. Use
+// the returned media's ID as the MediaID of one of a post's Items.
+func UploadMedia(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+	maxBytes := maxMediaBytes()
+
+	// Cap the request body so oversized files are not read in full; leave room for the multipart headers
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max(maxBytes, maxVideoBytes())+64<<10)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the file field", "details": err.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// Videos are told apart by their contents and stored without reading them into memory
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file", "details": err.Error()})
+		return
+	}
+	if contentType := utils.DetectVideoType(head[:n]); contentType != "" {
+		uploadVideo(c, file, header.Size, contentType)
+		return
+	}
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file", "details": err.Error()})
+		return
+	}
+
+	// Resize and store the image, then record the media
+	media := models.Media{UserID: viewer, Kind: models.MediaKindImage, Size: int64(len(data)), Status: models.MediaStatusReady}
+	if err := storeImageRenditions(c.Request.Context(), &media, data); err != nil {
+		respondImageError(c, err)
+		return
+	}
+	if err := db.Create(&media).Error; err != nil {
+		deleteStoredObjects(media.StorageKeys())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Media uploaded successfully", "media": media})
+}
+
+// CreateMediaUpload starts a direct upload: the client PUTs the file to the
+// returned URL with the same Content-Type, then calls CompleteMediaUpload.
+// Only storage backends that presign uploads, such as S3, support this.
+func CreateMediaUpload(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	var input struct {
+		ContentType string `json:"ContentType"`
+		Size        int64  `json:"Size"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	ext, ok := mediaTypes[input.ContentType]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "ContentType must be image/jpeg, image/png, image/gif, video/mp4, video/quicktime or video/webm"})
+		return
+	}
+	kind, maxBytes := mediaKind(input.ContentType), maxMediaBytes()
+	if kind == models.MediaKindVideo {
+		maxBytes = maxVideoBytes()
+	}
+	if input.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+		return
+	}
+
+	store := config.GetStorage()
+	presigner, ok := store.(storage.Presigner)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads are not available; upload through POST /media"})
+		return
+	}
+
+	key, err := storage.NewKey(mediaKeyPrefix(viewer), ext)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start upload", "details": err.Error()})
+		return
+	}
+	uploadURL, err := presigner.PresignPut(key, input.ContentType, mediaUploadExpiry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start upload", "details": err.Error()})
+		return
+	}
+
+	// Record the media as pending until the client reports the upload is done
+	media := models.Media{
+		UserID:      viewer,
+		Kind:        kind,
+		Key:         key,
+		URL:         store.URL(key),
+		ContentType: input.ContentType,
+		Status:      models.MediaStatusPending,
+	}
+	if err := db.Create(&media).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"media":      media,
+		"upload_url": uploadURL,
+		"method":     http.MethodPut,
+		"headers":    gin.H{"Content-Type": input.ContentType},
+		"expires_at": time.Now().Add(mediaUploadExpiry).UTC(),
+	})
+}
+
+// CompleteMediaUpload checks a direct upload arrived, is within the size
+// limit and really is the image type it was started with, then processes it
+// like UploadMedia and marks the media ready for use in posts. The raw file
+// is deleted either way. Videos are only checked for their type here and
+// queued for transcoding, see completeVideoUpload.
+func CompleteMediaUpload(c *gin.Context) {
+	db := config.GetDB()
+
+	var media models.Media
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), viewerID(c)).First(&media).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	if media.Status != models.MediaStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Media upload is already complete"})
+		return
+	}
+	if media.Kind == models.MediaKindVideo {
+		completeVideoUpload(c, &media)
+		return
+	}
+
+	// Read the uploaded file back, one byte past the limit to catch oversized files
+	object, err := config.GetStorage().Get(c.Request.Context(), media.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "File has not been uploaded yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(object, maxMediaBytes()+1))
+	object.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+
+	rawKey := media.Key
+	rejection := ""
+	switch {
+	case int64(len(data)) > maxMediaBytes():
+		rejection = "File is too large"
+	case http.DetectContentType(data) != media.ContentType:
+		rejection = "File is not the image type the upload was started with"
+	}
+
+	// Resize and store the image; storage failures leave the upload pending so it can be retried
+	if rejection == "" {
+		media.Size = int64(len(data))
+		if err := storeImageRenditions(c.Request.Context(), &media, data); err != nil {
+			if !isImageError(err) {
+				respondImageError(c, err)
+				return
+			}
+			rejection = "Invalid image: " + err.Error()
+		}
+	}
+	if rejection != "" {
+		if err := db.Delete(&media).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media", "details": err.Error()})
+			return
+		}
+		deleteStoredObjects([]string{rawKey})
+		c.JSON(http.StatusBadRequest, gin.H{"error": rejection})
+		return
+	}
+
+	media.Status = models.MediaStatusReady
+	if err := db.Save(&media).Error; err != nil {
+		deleteStoredObjects(media.StorageKeys())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media", "details": err.Error()})
+		return
+	}
+	deleteStoredObjects([]string{rawKey})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media upload completed successfully", "media": media})
+}
+
+// GetMedia returns one of the logged in user's uploads
+func GetMedia(c *gin.Context) {
+	var media models.Media
+	if err := config.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), viewerID(c)).First(&media).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"media": media})
+}
+
+// DeleteMedia removes one of the logged in user's uploads that no post uses.
+// Media in a post goes when the post is deleted.
+func DeleteMedia(c *gin.Context) {
+	db := config.GetDB()
+
+	var media models.Media
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), viewerID(c)).First(&media).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	var used int64
+	if err := db.Model(&models.PostMedia{}).Where("media_id = ?", media.ID).Count(&used).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media", "details": err.Error()})
+		return
+	}
+	if used > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Media is used in a post; delete the post instead"})
+		return
+	}
+
+	var keys []string
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		keys, err = deleteMediaRecords(tx, []uint{media.ID})
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media", "details": err.Error()})
+		return
+	}
+	deleteStoredObjects(keys)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
+
+// findAttachableMedia loads a finished upload of the user's that no other
+// post uses, counting videos still processing as finished; exceptPostID lets
+// a post keep its own media when edited
+func findAttachableMedia(db *gorm.DB, mediaID, userID, exceptPostID uint) (*models.Media, error) {
+	var media models.Media
+	if err := db.Where("id = ? AND user_id = ? AND status IN ?", mediaID, userID, []string{models.MediaStatusReady, models.MediaStatusProcessing}).
+		Where("NOT EXISTS (SELECT 1 FROM post_media WHERE post_media.media_id = media.id AND post_media.post_id <> ?)", exceptPostID).
+		First(&media).Error; err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// storeImageRenditions processes an uploaded image with utils.ProcessImage
+// and stores its renditions, pointing media's key and URL at the full one.
+// The original file, metadata and all, is never kept.
+func storeImageRenditions(ctx context.Context, media *models.Media, data []byte) error {
+	processed, err := utils.ProcessImage(data)
+	if err != nil {
+		return err
+	}
+
+	store := config.GetStorage()
+	base, err := storage.NewKey(mediaKeyPrefix(media.UserID), "")
+	if err != nil {
+		return err
+	}
+	renditions := map[string]models.MediaRendition{}
+	var stored []string
+	for _, rendition := range processed.Renditions {
+		key := base + "_" + rendition.Name + ".jpg"
+		if err := store.Put(ctx, key, bytes.NewReader(rendition.Data), int64(len(rendition.Data)), "image/jpeg"); err != nil {
+			deleteStoredObjects(stored)
+			return err
+		}
+		stored = append(stored, key)
+		renditions[rendition.Name] = models.MediaRendition{Key: key, URL: store.URL(key), Width: rendition.Width, Height: rendition.Height}
+	}
+
+	full := renditions[utils.RenditionFull]
+	media.Key, media.URL, media.ContentType = full.Key, full.URL, "image/jpeg"
+	media.Width, media.Height, media.Renditions = processed.Width, processed.Height, renditions
+	return nil
+}
+
+// isImageError reports whether err is a problem with the image itself rather than with storing it
+func isImageError(err error) bool {
+	return errors.Is(err, utils.ErrUnsupportedImage) || errors.Is(err, utils.ErrCorruptImage) || errors.Is(err, utils.ErrImageTooLarge)
+}
+
+// respondImageError reports a failure from storeImageRenditions
+func respondImageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File must be a JPEG, PNG or GIF image, or an MP4, QuickTime or WebM video"})
+	case isImageError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image", "details": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image", "details": err.Error()})
+	}
+}
+
+// deleteMediaRecords removes the media rows with the given IDs and their video
+// jobs inside tx and returns their storage keys, to delete once the
+// transaction commits
+func deleteMediaRecords(tx *gorm.DB, ids []uint) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var media []models.Media
+	if err := tx.Select("id", "key", "renditions").Where("id IN ?", ids).Find(&media).Error; err != nil {
+		return nil, err
+	}
+	var keys []string
+	for i := range media {
+		keys = append(keys, media[i].StorageKeys()...)
+	}
+	if err := tx.Where("media_id IN ?", ids).Delete(&models.VideoJob{}).Error; err != nil {
+		return nil, err
+	}
+	return keys, tx.Where("id IN ?", ids).Delete(&models.Media{}).Error
+}
+
+// deleteStoredObjects removes stored files, logging failures; a file left
+// behind wastes space but breaks nothing
+func deleteStoredObjects(keys []string) {
+	store := config.GetStorage()
+	for _, key := range keys {
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Println("Error deleting stored file", key, ":", err)
+		}
+	}
+}
+
+// CleanupOrphanedMedia deletes uploads no post uses once they are older than
+// MEDIA_ORPHAN_TTL, including direct uploads that were never completed
+func CleanupOrphanedMedia() {
+	db := config.GetDB()
+
+	log.Println("Running CleanupOrphanedMedia at:", time.Now().UTC())
+
+	ttl, err := time.ParseDuration(utils.GetEnv("MEDIA_ORPHAN_TTL", defaultMediaOrphanTTL.String()))
+	if err != nil || ttl <= 0 {
+		ttl = defaultMediaOrphanTTL
+	}
+
+	var ids []uint
+	if err := db.Model(&models.Media{}).
+		Where("created_at < ?", time.Now().Add(-ttl)).
+		Where("NOT EXISTS (SELECT 1 FROM post_media WHERE post_media.media_id = media.id)").
+		Pluck("id", &ids).Error; err != nil {
+		log.Println("Error finding orphaned media:", err)
+		return
+	}
+
+	keys, err := deleteMediaRecords(db, ids)
+	if err != nil {
+		log.Println("Error deleting orphaned media:", err)
+		return
+	}
+	deleteStoredObjects(keys)
+	log.Println("Deleted orphaned media (files:", len(keys), ")")
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"pixi/models"
+	"pixi/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// mentionSource identifies the text mentions were written in. PostID is always
+// set; CommentID is also set for mentions in comments and replies.
+type mentionSource struct {
+	AuthorID  uint
+	PostID    uint
+	CommentID *uint
+}
+
+// scope limits a query on mentions or notifications to this source
+func (source mentionSource) scope(db *gorm.DB) *gorm.DB {
+	if source.CommentID != nil {
+		return db.Where("comment_id = ?", *source.CommentID)
+	}
+	return db.Where("post_id = ? AND comment_id IS NULL", source.PostID)
+}
+
+// syncMentions replaces the mentions stored for source with the ones found in
+// text. Mentions of the author, of users on the other side of a block, of
+// users whose mention policy rejects the author and of users who may not see
+// the post are left as plain text. When notify is set, users mentioned for
+// the first time get a notification.
+func syncMentions(tx *gorm.DB, source mentionSource, text string, notify bool) ([]models.Mention, error) {
+	mentions := []models.Mention{}
+	ranges := utils.ExtractMentions(text)
+
+	// Resolve the usernames, case-insensitively
+	usersByName := map[string]models.User{}
+	if len(ranges) > 0 {
+		names := make([]string, len(ranges))
+		for i, r := range ranges {
+			names[i] = strings.ToLower(r.Username)
+		}
+		var users []models.User
+		if err := tx.Where("LOWER(username) IN ?", names).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			usersByName[strings.ToLower(user.Username)] = user
+		}
+	}
+
+	// Decide once per user whether they may be mentioned by the author and see the post
+	allowed := map[uint]bool{}
+	if len(usersByName) > 0 {
+		post, err := mentionedPost(tx, source)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range usersByName {
+			ok, err := canMention(tx, source.AuthorID, &user)
+			if err == nil && ok {
+				ok, err = canViewPost(tx, post, user.ID)
+			}
+			if err != nil {
+				return nil, err
+			}
+			allowed[user.ID] = ok
+		}
+	}
+
+	for _, r := range ranges {
+		user, found := usersByName[strings.ToLower(r.Username)]
+		if !found || !allowed[user.ID] {
+			continue
+		}
+		mention := models.Mention{
+			MentionedID: user.ID,
+			MentionerID: source.AuthorID,
+			Username:    r.Username,
+			Start:       r.Start,
+			End:         r.End,
+		}
+		if source.CommentID != nil {
+			mention.CommentID = source.CommentID
+		} else {
+			mention.PostID = &source.PostID
+		}
+		mentions = append(mentions, mention)
+	}
+
+	// Remember who was already mentioned so edits do not notify them again
+	var previousIDs []uint
+	if err := tx.Model(&models.Mention{}).Scopes(source.scope).Pluck("mentioned_id", &previousIDs).Error; err != nil {
+		return nil, err
+	}
+	notified := map[uint]bool{}
+	for _, id := range previousIDs {
+		notified[id] = true
+	}
+
+	// Replace the stored mentions
+	if err := tx.Scopes(source.scope).Delete(&models.Mention{}).Error; err != nil {
+		return nil, err
+	}
+	if len(mentions) > 0 {
+		if err := tx.Create(&mentions).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if !notify {
+		return mentions, nil
+	}
+	for _, mention := range mentions {
+		if notified[mention.MentionedID] {
+			continue
+		}
+		notified[mention.MentionedID] = true
+		if err := notifyMention(tx, source, mention.MentionedID); err != nil {
+			return nil, err
+		}
+	}
+	return mentions, nil
+}
+
+// notifyStoredMentions notifies everyone mentioned in source who may see the
+// post, for posts whose mentions were stored before they were published
+func notifyStoredMentions(tx *gorm.DB, source mentionSource) error {
+	var mentionedIDs []uint
+	if err := tx.Model(&models.Mention{}).Scopes(source.scope).Distinct().Pluck("mentioned_id", &mentionedIDs).Error; err != nil {
+		return err
+	}
+	if len(mentionedIDs) == 0 {
+		return nil
+	}
+	post, err := mentionedPost(tx, source)
+	if err != nil {
+		return err
+	}
+	for _, id := range mentionedIDs {
+		visible, err := canViewPost(tx, post, id)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			continue
+		}
+		if err := notifyMention(tx, source, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mentionedPost loads the post source belongs to, for checking who may see
+// it. It is treated as published, so mentions in scheduled posts are judged
+// by who will see them once they go out.
+func mentionedPost(tx *gorm.DB, source mentionSource) (*models.Post, error) {
+	var post models.Post
+	if err := tx.Preload("User").First(&post, source.PostID).Error; err != nil {
+		return nil, err
+	}
+	post.Status = "published"
+	return &post, nil
+}
+
+// notifyMention tells a user they were mentioned in source
+func notifyMention(tx *gorm.DB, source mentionSource, userID uint) error {
+	postID := source.PostID
+	return tx.Create(&models.Notification{
+		UserID:    userID,
+		ActorID:   source.AuthorID,
+		Type:      models.NotificationMention,
+		PostID:    &postID,
+		CommentID: source.CommentID,
+	}).Error
+}
+
+// deleteMentions removes the mentions in source and the notifications they caused
+func deleteMentions(tx *gorm.DB, source mentionSource) error {
+	if err := tx.Scopes(source.scope).Delete(&models.Mention{}).Error; err != nil {
+		return err
+	}
+	return tx.Scopes(source.scope).Where("type = ?", models.NotificationMention).Delete(&models.Notification{}).Error
+}
+
+// canMention reports whether author may mention user, honouring blocks in
+// either direction and the user's mention policy
+func canMention(db *gorm.DB, authorID uint, user *models.User) (bool, error) {
+	if user.ID == authorID {
+		return false, nil
+	}
+
+	blocked, err := isBlocked(db, authorID, user.ID)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	switch user.MentionPolicy {
+	case models.MentionPolicyNobody:
+		return false, nil
+	case models.MentionPolicyFollowing:
+		return isFollowing(db, user.ID, authorID)
+	}
+	return true, nil
+}
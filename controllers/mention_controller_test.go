@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"pixi/models"
+	"testing"
+)
+
+func TestSyncMentionsSkipsUsersWhoCannotSeeThePost(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	friend := &models.User{FullName: "Friend", Username: "friend", Email: "friend@example.com", Password: "x"}
+	stranger := &models.User{FullName: "Stranger", Username: "stranger", Email: "stranger@example.com", Password: "x"}
+	mustCreate(t, db, author, friend, stranger)
+	mustCreate(t, db, &models.CloseFriend{UserID: author.ID, FriendID: friend.ID})
+
+	caption := "Dinner with @friend and @Stranger"
+	post := &models.Post{Caption: caption, ImageURL: "post", UserID: author.ID, Status: "published", Audience: models.AudienceCloseFriends}
+	mustCreate(t, db, post)
+
+	mentions, err := syncMentions(db, mentionSource{AuthorID: author.ID, PostID: post.ID}, caption, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 || mentions[0].MentionedID != friend.ID {
+		t.Fatalf("mentions = %+v, want only @friend", mentions)
+	}
+
+	var notified []uint
+	if err := db.Model(&models.Notification{}).Where("type = ?", models.NotificationMention).Pluck("user_id", &notified).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0] != friend.ID {
+		t.Fatalf("notified users %v, want only %d", notified, friend.ID)
+	}
+}
+
+func TestNotifyStoredMentionsSkipsUsersWhoCannotSeeThePost(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	follower := &models.User{FullName: "Follower", Username: "follower", Email: "follower@example.com", Password: "x"}
+	stranger := &models.User{FullName: "Stranger", Username: "stranger", Email: "stranger@example.com", Password: "x"}
+	mustCreate(t, db, author, follower, stranger)
+	mustCreate(t, db, &models.Follow{FollowerID: follower.ID, FollowingID: author.ID, Status: models.FollowStatusAccepted})
+
+	// A scheduled followers-only post stores its mentions without notifying
+	caption := "Soon: @follower and @stranger"
+	post := &models.Post{Caption: caption, ImageURL: "post", UserID: author.ID, Status: "scheduled", Audience: models.AudienceFollowers}
+	mustCreate(t, db, post)
+	source := mentionSource{AuthorID: author.ID, PostID: post.ID}
+	if _, err := syncMentions(db, source, caption, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mentions stored before visibility was checked still notify only those who may see the post
+	mustCreate(t, db, &models.Mention{MentionedID: stranger.ID, MentionerID: author.ID, PostID: &post.ID, Username: "stranger", Start: 20, End: 29})
+	if err := notifyStoredMentions(db, source); err != nil {
+		t.Fatal(err)
+	}
+
+	var notified []uint
+	if err := db.Model(&models.Notification{}).Where("type = ?", models.NotificationMention).Pluck("user_id", &notified).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0] != follower.ID {
+		t.Fatalf("notified users %v, want only %d", notified, follower.ID)
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"pixi/models"
+	"testing"
+)
+
+func TestMigrateLegacyDataFoldsReplies(t *testing.T) {
+	db := newTestDB(t)
+
+	// The replies table and reply columns as they were before threaded comments
+	for _, statement := range []string{
+		"CREATE TABLE replies (id integer PRIMARY KEY AUTOINCREMENT, post_id integer NOT NULL, user_id integer NOT NULL, comment_id integer," +
+			" content text NOT NULL, audio_content text, created_at datetime DEFAULT CURRENT_TIMESTAMP, updated_at datetime DEFAULT CURRENT_TIMESTAMP)",
+		"ALTER TABLE mentions ADD COLUMN reply_id integer",
+		"ALTER TABLE notifications ADD COLUMN reply_id integer",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	fan := &models.User{FullName: "Fan", Username: "fan", Email: "fan@example.com", Password: "x"}
+	mustCreate(t, db, author, fan)
+	post := &models.Post{Caption: "post", ImageURL: "post", UserID: author.ID, Status: "published"}
+	mustCreate(t, db, post)
+	comment := &models.Comment{Author: "fan", Content: "First", PostID: post.ID, UserID: fan.ID}
+	mustCreate(t, db, comment)
+	comment.Path = models.CommentPath("", comment.ID)
+	if err := db.Model(comment).Update("path", comment.Path).Error; err != nil {
+		t.Fatal(err)
+	}
+	hidden := &models.Comment{Author: "fan", Content: "Filtered", PostID: post.ID, UserID: fan.ID, Hidden: true, HiddenReason: models.HiddenByFilter}
+	mustCreate(t, db, hidden)
+
+	// A reply to a live comment, one whose comment is gone, and one with a mention and a notification
+	for _, reply := range []struct {
+		commentID uint
+		content   string
+	}{
+		{comment.ID, "Live parent"},
+		{comment.ID + 1000, "Orphaned"},
+		{comment.ID, "Thanks @author"},
+	} {
+		if err := db.Exec("INSERT INTO replies (post_id, user_id, comment_id, content) VALUES (?, ?, ?, ?)",
+			post.ID, fan.ID, reply.commentID, reply.content).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("INSERT INTO mentions (mentioned_id, mentioner_id, username, start, end, reply_id) VALUES (?, ?, 'author', 7, 14, 3)",
+		author.ID, fan.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO notifications (user_id, actor_id, type, post_id, reply_id) VALUES (?, ?, ?, ?, 3)",
+		author.ID, fan.ID, models.NotificationMention, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.MigrateLegacyData(db); err != nil {
+		t.Fatalf("MigrateLegacyData: %v", err)
+	}
+
+	if db.Migrator().HasTable("replies") {
+		t.Fatal("replies table still exists")
+	}
+
+	folded := map[string]models.Comment{}
+	var comments []models.Comment
+	if err := db.Where("id NOT IN ?", []uint{comment.ID, hidden.ID}).Find(&comments).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range comments {
+		folded[c.Content] = c
+	}
+	if len(folded) != 3 {
+		t.Fatalf("folded replies = %+v, want 3", comments)
+	}
+
+	for _, content := range []string{"Live parent", "Thanks @author"} {
+		reply := folded[content]
+		if reply.ParentID == nil || *reply.ParentID != comment.ID || reply.Depth != 1 ||
+			reply.Path != models.CommentPath(comment.Path, reply.ID) || reply.Author != "fan" {
+			t.Errorf("%q = %+v, want a reply by fan under comment %d", content, reply, comment.ID)
+		}
+	}
+	orphan := folded["Orphaned"]
+	if orphan.ParentID != nil || orphan.Depth != 0 || orphan.Path != models.CommentPath("", orphan.ID) {
+		t.Errorf("orphaned reply = %+v, want a top-level comment", orphan)
+	}
+
+	// The mention and notification point at the folded comment
+	thanks := folded["Thanks @author"].ID
+	var mention models.Mention
+	if err := db.First(&mention).Error; err != nil {
+		t.Fatal(err)
+	}
+	if mention.CommentID == nil || *mention.CommentID != thanks {
+		t.Errorf("mention points at comment %v, want %d", mention.CommentID, thanks)
+	}
+	var notification models.Notification
+	if err := db.First(&notification).Error; err != nil {
+		t.Fatal(err)
+	}
+	if notification.CommentID == nil || *notification.CommentID != thanks {
+		t.Errorf("notification points at comment %v, want %d", notification.CommentID, thanks)
+	}
+	var dangling int64
+	if err := db.Raw("SELECT COUNT(*) FROM mentions WHERE reply_id IS NOT NULL").Scan(&dangling).Error; err != nil {
+		t.Fatal(err)
+	}
+	if dangling != 0 {
+		t.Errorf("%d mentions still point at a reply", dangling)
+	}
+
+	// Counters include the folded replies but not the hidden comment
+	var parent models.Comment
+	if err := db.First(&parent, comment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if parent.ReplyCount != 2 {
+		t.Errorf("parent ReplyCount = %d, want 2", parent.ReplyCount)
+	}
+	var counted models.Post
+	if err := db.First(&counted, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if counted.CommentCount != 4 {
+		t.Errorf("post CommentCount = %d, want 4", counted.CommentCount)
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMutes lists the users the logged in user has muted
+func GetMutes(c *gin.Context) {
+	db := config.GetDB()
+	var mutes []models.Mute
+
+	if err := db.Preload("Muted", publicUserColumns).Where("muter_id = ?", viewerID(c)).Order("created_at DESC").Find(&mutes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve muted users", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mutes)
+}
+
+// CreateMute mutes a user, or updates what is muted if they already are.
+// With no options given only the user's posts are muted.
+func CreateMute(c *gin.Context) {
+	// Options are pointers so omitted ones keep their current value
+	var input struct {
+		MutedID      uint  `json:"MutedID"`
+		MutePosts    *bool `json:"MutePosts"`
+		MuteStories  *bool `json:"MuteStories"`
+		MuteComments *bool `json:"MuteComments"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	// Validate required fields
+	if input.MutedID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Muted ID is required"})
+		return
+	}
+
+	// Check if a user is trying to mute themselves
+	muterID := viewerID(c)
+	if input.MutedID == muterID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A user cannot mute themselves"})
+		return
+	}
+
+	// Get the DB connection
+	db := config.GetDB()
+
+	// Ensure the user to mute exists
+	var muted models.User
+	if err := db.First(&muted, input.MutedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User to mute not found"})
+		return
+	}
+
+	// Start from the existing mute, if any
+	var mute models.Mute
+	err := db.Where("muter_id = ? AND muted_id = ?", muterID, input.MutedID).First(&mute).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking mute", "details": err.Error()})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		mute = models.Mute{MuterID: muterID, MutedID: input.MutedID}
+		if input.MutePosts == nil && input.MuteStories == nil && input.MuteComments == nil {
+			mute.MutePosts = true
+		}
+	}
+
+	// Apply the requested options
+	if input.MutePosts != nil {
+		mute.MutePosts = *input.MutePosts
+	}
+	if input.MuteStories != nil {
+		mute.MuteStories = *input.MuteStories
+	}
+	if input.MuteComments != nil {
+		mute.MuteComments = *input.MuteComments
+	}
+	if !mute.MutePosts && !mute.MuteStories && !mute.MuteComments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one of posts, stories or comments must be muted"})
+		return
+	}
+
+	// Save the mute to the database
+	if err := db.Save(&mute).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mute user", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User muted successfully", "mute": mute})
+}
+
+// DeleteMute unmutes a user by their user ID
+func DeleteMute(c *gin.Context) {
+	db := config.GetDB()
+
+	result := db.Where("muter_id = ? AND muted_id = ?", viewerID(c), c.Param("userID")).Delete(&models.Mute{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmute user", "details": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mute not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unmuted successfully"})
+}
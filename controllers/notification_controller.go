@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxNotificationLimit caps how many notifications one request may return
+const maxNotificationLimit = 100
+
+// GetNotifications lists the logged in user's notifications, newest first
+func GetNotifications(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	// Retrieve query parameters for infinite scroll; last_notification_id is the oldest one loaded so far
+	lastID, err := strconv.Atoi(c.DefaultQuery("last_notification_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_notification_id"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxNotificationLimit {
+		limit = maxNotificationLimit
+	}
+
+	// Notifications from users on the other side of a block are hidden
+	query := db.Preload("Actor", publicUserColumns).Scopes(notBlocked(viewer, "notifications.actor_id")).Where("user_id = ?", viewer)
+	if lastID > 0 {
+		query = query.Where("id < ?", lastID)
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("id DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications", "details": err.Error()})
+		return
+	}
+
+	// Count the unread ones for the badge
+	var unread int64
+	if err := db.Model(&models.Notification{}).Scopes(notBlocked(viewer, "notifications.actor_id")).
+		Where("user_id = ? AND read = ?", viewer, false).Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notifications", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread_count": unread})
+}
+
+// MarkNotificationsRead marks the logged in user's notifications as read, either
+// the ones listed in IDs or all of them when IDs is omitted
+func MarkNotificationsRead(c *gin.Context) {
+	var input struct {
+		IDs []uint `json:"IDs"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	query := config.GetDB().Model(&models.Notification{}).Where("user_id = ? AND read = ?", viewerID(c), false)
+	if len(input.IDs) > 0 {
+		query = query.Where("id IN ?", input.IDs)
+	}
+
+	result := query.Update("read", true)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read", "details": result.Error.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "updated": result.RowsAffected})
+}
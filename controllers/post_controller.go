@@ -3,13 +3,14 @@ package controllers
 import (
 	"log"
 	"net/http"
-	"net/url"
 	"pixi/config"
 	"pixi/models"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
 // GetPost handles the retrieval of all posts
@@ -33,9 +34,26 @@ func GetPost(c *gin.Context) {
 		return
 	}
 
+	query := config.DB.Preload("User").Preload("Hashtags").Preload("Mentions").Scopes(withPostItems).
+		Scopes(visiblePosts(viewerID(c)), notMuted(viewerID(c), "posts.user_id", "mute_posts"))
+
+	// The home feed is limited to the viewer's own posts, accounts they follow and hashtags they follow
+	if c.Query("feed") == "home" {
+		viewer := viewerID(c)
+		if viewer == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Log in to see your home feed"})
+			return
+		}
+		query = query.Where(
+			"(posts.user_id = ?"+
+				" OR posts.user_id IN (SELECT following_id FROM follows WHERE follower_id = ? AND status = ?)"+
+				" OR posts.id IN (SELECT post_id FROM post_hashtags WHERE hashtag_id IN (SELECT hashtag_id FROM hashtag_follows WHERE user_id = ?)))",
+			viewer, viewer, models.FollowStatusAccepted, viewer,
+		)
+	}
+
 	// Fetch posts after the last loaded post
-	result := config.DB.Preload("User").Preload("Likes").Preload("Comments").
-		Where("status = ? AND id > ?", "published", lastPostIDInt).
+	result := query.Where("status = ? AND id > ?", "published", lastPostIDInt).
 		Limit(limitInt).Find(&posts)
 
 	// Check for errors
@@ -44,6 +62,15 @@ func GetPost(c *gin.Context) {
 		return
 	}
 
+	// Add reaction counts, hiding them on posts whose author asked for it
+	if err := attachPostReactions(config.DB, viewerID(c), posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range posts {
+		applyLikeVisibility(&posts[i], viewerID(c))
+	}
+
 	// Respond with the retrieved posts
 	c.JSON(http.StatusOK, gin.H{"posts": posts})
 }
@@ -59,48 +86,93 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
-	// Assign the UserID from the context to the new post
-	newPost.UserID = userID.(uint)
+	// Bind the request to the newPost object
+	if err := bindNewPost(c, &newPost); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
 
-	// Assing the Post status as published
+	// The ID, author and status are set server-side, never taken from the client
+	newPost.ID = 0
+	newPost.UserID = userID.(uint)
 	newPost.Status = "published"
 
-	// Bind the request to the newPost object
-	if err := c.ShouldBindJSON(&newPost); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	// Counters are maintained server-side and never taken from the client
+	newPost.LikeCount, newPost.CommentCount, newPost.SaveCount = 0, 0, 0
+
+	// Hashtags and mentions are parsed from the text, never taken from the client
+	newPost.Hashtags, newPost.Mentions = nil, nil
+
+	// Validate the audience, falling back to the legacy IsPrivate flag
+	if !newPost.NormalizeAudience() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audience must be public, followers or close_friends"})
 		return
 	}
 
-	// Validate required fields (example: ensure caption and image URL are not empty)
-	if newPost.Caption == "" || newPost.ImageURL == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Caption and Image URL are required"})
+	// Validate the comment policy, falling back to the legacy AllowComments flag
+	if !newPost.NormalizeCommentPolicy() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CommentPolicy must be everyone, followers, following or off"})
 		return
 	}
 
-	// Optionally, validate the image URL format
-	if _, err := url.ParseRequestURI(newPost.ImageURL); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Image URL format"})
+	// Validate required fields; images must be uploaded through the media endpoints first
+	requestedItems := postItemsOrCover(newPost.Items, newPost.MediaID)
+	if newPost.Caption == "" || len(requestedItems) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Caption and Items are required"})
 		return
 	}
 
 	// Get the DB connection
 	db := config.GetDB()
 
-	// Save the new post to the database
-	if err := db.Create(&newPost).Error; err != nil {
+	// Show the uploaded images and videos, never URLs taken from the client
+	items, ok := buildPostItems(c, db, &newPost, requestedItems)
+	if !ok {
+		return
+	}
+
+	// Hold the post back until its videos are processed; ProcessVideoJobs publishes it
+	if itemsProcessing(items) {
+		newPost.Status = "processing"
+	}
+
+	// Save the new post and bump the author's post count together
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Media", "Items").Create(&newPost).Error; err != nil {
+			return err
+		}
+		if err := savePostItems(tx, newPost.ID, items); err != nil {
+			return err
+		}
+		if err := syncPostHashtags(tx, &newPost); err != nil {
+			return err
+		}
+		published := newPost.Status == "published"
+		if _, err := syncMentions(tx, mentionSource{AuthorID: newPost.UserID, PostID: newPost.ID}, newPost.Caption, published); err != nil {
+			return err
+		}
+		if !published {
+			return nil
+		}
+		return adjustCounter(tx, &models.User{}, newPost.UserID, "post_count", 1)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving post", "details": err.Error()})
 		return
 	}
 
 	// Retrieve the saved post with preloading
 	var createdPost models.Post
-	if err := db.Preload("User").Preload("Likes").First(&createdPost, newPost.ID).Error; err != nil {
+	if err := db.Preload("User").Preload("Hashtags").Preload("Mentions").Scopes(withPostItems).First(&createdPost, newPost.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving post with associations", "details": err.Error()})
 		return
 	}
 
 	// Respond with the created post including preloaded associations
-	c.JSON(http.StatusCreated, gin.H{"message": "Post created successfully", "post": createdPost})
+	message := "Post created successfully"
+	if createdPost.Status == "processing" {
+		message = "Post created successfully; it will be published once its videos are processed"
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": message, "post": createdPost})
 }
 
 // CreateScheduledPost handles creating a scheduled post
@@ -114,21 +186,39 @@ func CreateScheduledPost(c *gin.Context) {
 		return
 	}
 
-	// Assign the UserID from the context to the new post
-	newPost.UserID = userID.(uint)
+	// Bind the request to the newPost object
+	if err := bindNewPost(c, &newPost); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
 
-	// Assing the Post status as scheduled
+	// The ID, author and status are set server-side, never taken from the client
+	newPost.ID = 0
+	newPost.UserID = userID.(uint)
 	newPost.Status = "scheduled"
 
-	// Bind the request to the newPost object
-	if err := c.ShouldBindJSON(&newPost); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+	// Counters are maintained server-side and never taken from the client
+	newPost.LikeCount, newPost.CommentCount, newPost.SaveCount = 0, 0, 0
+
+	// Hashtags and mentions are parsed from the text, never taken from the client
+	newPost.Hashtags, newPost.Mentions = nil, nil
+
+	// Validate the audience, falling back to the legacy IsPrivate flag
+	if !newPost.NormalizeAudience() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audience must be public, followers or close_friends"})
 		return
 	}
 
-	// Validate required fields
-	if newPost.Caption == "" || newPost.ImageURL == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Caption and Image URL are required"})
+	// Validate the comment policy, falling back to the legacy AllowComments flag
+	if !newPost.NormalizeCommentPolicy() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CommentPolicy must be everyone, followers, following or off"})
+		return
+	}
+
+	// Validate required fields; images must be uploaded through the media endpoints first
+	requestedItems := postItemsOrCover(newPost.Items, newPost.MediaID)
+	if newPost.Caption == "" || len(requestedItems) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Caption and Items are required"})
 		return
 	}
 
@@ -141,9 +231,31 @@ func CreateScheduledPost(c *gin.Context) {
 	// Convert ScheduledAt to UTC
 	newPost.ScheduledAt = newPost.ScheduledAt.UTC()
 
-	// Save the new scheduled post to the database
+	// Show the uploaded images and videos, never URLs taken from the client;
+	// videos still processing when the post is due hold it back until they are done
 	db := config.GetDB()
-	if err := db.Create(&newPost).Error; err != nil {
+	items, ok := buildPostItems(c, db, &newPost, requestedItems)
+	if !ok {
+		return
+	}
+
+	// Save the new scheduled post and index its hashtags and mentions; mentioned
+	// users are notified when it is published
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Media", "Items").Create(&newPost).Error; err != nil {
+			return err
+		}
+		if err := savePostItems(tx, newPost.ID, items); err != nil {
+			return err
+		}
+		newPost.Items = items
+		if err := syncPostHashtags(tx, &newPost); err != nil {
+			return err
+		}
+		mentions, err := syncMentions(tx, mentionSource{AuthorID: newPost.UserID, PostID: newPost.ID}, newPost.Caption, false)
+		newPost.Mentions = mentions
+		return err
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving post", "details": err.Error()})
 		return
 	}
@@ -152,6 +264,25 @@ func CreateScheduledPost(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Scheduled post created successfully", "post": newPost})
 }
 
+// bindNewPost binds a post being created. Comments are open unless the
+// client sets CommentPolicy, or turns them off with the legacy AllowComments
+// flag, which is only honoured when it is actually sent.
+func bindNewPost(c *gin.Context, post *models.Post) error {
+	if err := c.ShouldBindBodyWith(post, binding.JSON); err != nil {
+		return err
+	}
+	var legacy struct {
+		AllowComments *bool `json:"AllowComments"`
+	}
+	if err := c.ShouldBindBodyWith(&legacy, binding.JSON); err != nil {
+		return err
+	}
+	if post.CommentPolicy == "" && legacy.AllowComments != nil && !*legacy.AllowComments {
+		post.CommentPolicy = models.CommentPolicyOff
+	}
+	return nil
+}
+
 // Cron job or background task to check scheduled posts
 func PublishScheduledPosts() {
 	db := config.GetDB()
@@ -167,11 +298,25 @@ func PublishScheduledPosts() {
 
 	log.Println("Number of posts to publish:", len(posts))
 
-	// Loop through and publish each scheduled post
+	// Loop through and publish each scheduled post, or hold it back while its videos are processed
 	for _, post := range posts {
 		log.Println("Publishing post (ID:", post.ID, "ScheduledAt:", post.ScheduledAt, ")")
-		post.Status = "published"
-		if err := db.Save(&post).Error; err != nil {
+		status, err := postItemsStatus(db, post.ID)
+		if err != nil {
+			log.Println("Error checking post media (ID:", post.ID, "):", err)
+			continue
+		}
+		if status != models.MediaStatusReady {
+			if err := db.Model(&post).Update("status", status).Error; err != nil {
+				log.Println("Error updating post status (ID:", post.ID, "):", err)
+			} else {
+				log.Println("Post media not ready (ID:", post.ID, "status:", status, ")")
+			}
+			continue
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return publishPost(tx, &post)
+		}); err != nil {
 			log.Println("Error publishing post (ID:", post.ID, "):", err)
 		} else {
 			log.Println("Post published successfully (ID:", post.ID, ")")
@@ -179,47 +324,146 @@ func PublishScheduledPosts() {
 	}
 }
 
+// publishPost publishes a scheduled or processing post: it counts towards
+// its hashtags and the author's post count, and mentioned users are notified
+func publishPost(tx *gorm.DB, post *models.Post) error {
+	post.Status = "published"
+	if err := tx.Model(post).Update("status", post.Status).Error; err != nil {
+		return err
+	}
+	hashtagIDs, err := postHashtagIDs(tx, post.ID)
+	if err != nil {
+		return err
+	}
+	if err := adjustHashtagCounts(tx, hashtagIDs, 1); err != nil {
+		return err
+	}
+	if err := recordHashtagUsage(tx, hashtagIDs, time.Now()); err != nil {
+		return err
+	}
+	if err := notifyStoredMentions(tx, mentionSource{AuthorID: post.UserID, PostID: post.ID}); err != nil {
+		return err
+	}
+	return adjustCounter(tx, &models.User{}, post.UserID, "post_count", 1)
+}
+
 // UpdatePost updates an existing post
 func UpdatePost(c *gin.Context) {
 	db := config.GetDB()
-	var post models.Post
 	postID := c.Param("id")
 
-	// Bind the input JSON data
+	// Bind the input JSON data; settings are pointers so an explicit false can be told apart from an omitted field
+	var post struct {
+		Caption        string              `json:"Caption"`
+		Items          *[]models.PostMedia `json:"Items"`   // Replaces all images when given
+		MediaID        *uint               `json:"MediaID"` // Deprecated: replaces all images with this one
+		AllowComments  *bool               `json:"AllowComments"`
+		CommentPolicy  string              `json:"CommentPolicy"`
+		HideLikeCounts *bool               `json:"HideLikeCounts"`
+		Audience       string              `json:"Audience"`
+	}
 	if err := c.ShouldBindJSON(&post); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
-	// Find the post by ID and preload the User relationship
+	// Find the post by ID and preload the User relationship and images
 	var existingPost models.Post
-	if err := db.Preload("User").First(&existingPost, postID).Error; err != nil {
+	if err := db.Preload("User").Scopes(withPostItems).First(&existingPost, postID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
+	// Only the author may edit the post
+	if existingPost.UserID != viewerID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can update this post"})
+		return
+	}
+
 	// Update the fields (caption, image URL, etc.) if they are provided
 	if post.Caption != "" {
 		existingPost.Caption = post.Caption
 	}
-	if post.ImageURL != "" {
-		existingPost.ImageURL = post.ImageURL
+	// A new list of images and videos replaces the old one; uploads no longer shown are deleted with the update
+	var items []models.PostMedia
+	publish := false
+	if post.Items != nil || post.MediaID != nil {
+		var requested []models.PostMedia
+		if post.Items != nil {
+			requested = *post.Items
+		}
+		var ok bool
+		if items, ok = buildPostItems(c, db, &existingPost, postItemsOrCover(requested, post.MediaID)); !ok {
+			return
+		}
+
+		// A published post stays up, so it can only show finished videos; a post
+		// held back or failed for its videos waits for the new ones, if any
+		switch existingPost.Status {
+		case "published":
+			if itemsProcessing(items) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Videos must finish processing before they are added to a published post"})
+				return
+			}
+		case "processing", "failed":
+			existingPost.Status = "processing"
+			publish = !itemsProcessing(items)
+		}
+	}
+	if post.CommentPolicy != "" || post.AllowComments != nil {
+		switch {
+		case post.CommentPolicy != "":
+			existingPost.CommentPolicy = post.CommentPolicy
+		case !*post.AllowComments:
+			existingPost.CommentPolicy = models.CommentPolicyOff
+		case existingPost.CommentPolicy == models.CommentPolicyOff:
+			// Turning comments back on with the legacy flag opens them to everyone
+			existingPost.CommentPolicy = models.CommentPolicyEveryone
+		}
+		if !existingPost.NormalizeCommentPolicy() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "CommentPolicy must be everyone, followers, following or off"})
+			return
+		}
+	}
+	if post.HideLikeCounts != nil {
+		existingPost.HideLikeCounts = *post.HideLikeCounts
 	}
-	if post.UserID != 0 { // Check if UserID is valid (not zero)
-		// Validate if the user exists
-		var user models.User
-		if err := db.First(&user, post.UserID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if post.Audience != "" {
+		existingPost.Audience = post.Audience
+		if !existingPost.NormalizeAudience() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Audience must be public, followers or close_friends"})
 			return
 		}
-		existingPost.UserID = post.UserID // Direct assignment, no need for indirection
 	}
 
-	// Update the post in the database
-	if err := db.Save(&existingPost).Error; err != nil {
+	// Update the post and re-index its hashtags and mentions, leaving the maintained counters untouched
+	var replacedKeys []string
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("LikeCount", "CommentCount", "SaveCount", "Hashtags", "Media", "Items").Save(&existingPost).Error; err != nil {
+			return err
+		}
+		if items != nil {
+			keys, err := replacePostItems(tx, existingPost.ID, items)
+			if err != nil {
+				return err
+			}
+			replacedKeys, existingPost.Items = keys, items
+		}
+		if err := syncPostHashtags(tx, &existingPost); err != nil {
+			return err
+		}
+		source := mentionSource{AuthorID: existingPost.UserID, PostID: existingPost.ID}
+		mentions, err := syncMentions(tx, source, existingPost.Caption, existingPost.Status == "published")
+		existingPost.Mentions = mentions
+		if err != nil || !publish {
+			return err
+		}
+		return publishPost(tx, &existingPost)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating post", "details": err.Error()})
 		return
 	}
+	deleteStoredObjects(replacedKeys)
 
 	// Respond with the updated post
 	c.JSON(http.StatusOK, gin.H{"message": "Post updated successfully", "post": existingPost})
@@ -232,12 +476,42 @@ func GetPostByID(c *gin.Context) {
 	postID := c.Param("id") // Get the post ID from the URL params
 
 	// Ensure the postID is an integer or valid for comparison in the query
-	if err := db.Preload("User").Preload("Comments").Preload("Comments.Replies").Preload("Comments.User").Preload("Comments.Replies.User").Preload("Comments.Replies.Author").Where("id = ?", postID).First(&post).Error; err != nil {
+	viewer := viewerID(c)
+	if err := db.Preload("User").Preload("Hashtags").Preload("Mentions").Scopes(withPostItems).
+		Where("id = ?", postID).First(&post).Error; err != nil {
 		// If the post is not found, return a 404 error
 		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 		return
 	}
 
+	// Unpublished posts, private posts and posts from private accounts are hidden from other users
+	visible, err := canViewPost(db, &post, viewer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking post visibility", "details": err.Error()})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	// Include the first page of comments; the rest are paged through the comments endpoint
+	comments, err := loadComments(db, viewer, post.ID, defaultCommentPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving comments", "details": err.Error()})
+		return
+	}
+	post.Comments = comments
+
+	// Add reaction counts, hiding them if the author asked for it
+	posts := []models.Post{post}
+	if err := attachPostReactions(db, viewer, posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving reactions", "details": err.Error()})
+		return
+	}
+	post = posts[0]
+	applyLikeVisibility(&post, viewer)
+
 	// Return the post as JSON
 	c.JSON(http.StatusOK, post)
 }
@@ -253,10 +527,72 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := db.Delete(&post).Error; err != nil {
+	// Only the author may delete the post
+	if post.UserID != viewerID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can delete this post"})
+		return
+	}
+
+	// Delete the post with everything attached to it, dropping the author's post count if it had been published
+	var storedKeys []string
+	if err := db.Transaction(func(tx *gorm.DB) (err error) {
+		storedKeys, err = deletePostRows(tx, &post)
+		return err
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting post", "details": err.Error()})
 		return
 	}
 
+	// Remove the images' and recordings' files only once the rows are gone for good
+	deleteStoredObjects(storedKeys)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
+
+// deletePostRows removes the post inside tx along with its tags, mentions,
+// notifications, comments, likes, saves and images, and returns the storage
+// keys of its files, to delete once the transaction commits. Rows referring
+// to the post go first so its foreign keys are not violated; its media go
+// last, as the post's legacy MediaID still refers to them.
+func deletePostRows(tx *gorm.DB, post *models.Post) ([]string, error) {
+	if err := setPostHashtags(tx, post, nil); err != nil {
+		return nil, err
+	}
+	if err := deleteMentions(tx, mentionSource{PostID: post.ID}); err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.ID).Delete(&models.Notification{}).Error; err != nil {
+		return nil, err
+	}
+	var comments []models.Comment
+	if err := tx.Select("id", "audio_id").Where("post_id = ?", post.ID).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	audioKeys, err := deleteComments(tx, comments)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.ID).Delete(&models.Like{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("post_id = ?", post.ID).Delete(&models.Save{}).Error; err != nil {
+		return nil, err
+	}
+	mediaIDs, err := deletePostItems(tx, post.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Delete(post).Error; err != nil {
+		return nil, err
+	}
+	mediaKeys, err := deleteMediaRecords(tx, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status == "published" {
+		if err := adjustCounter(tx, &models.User{}, post.UserID, "post_count", -1); err != nil {
+			return nil, err
+		}
+	}
+	return append(mediaKeys, audioKeys...), nil
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"pixi/models"
+	"sort"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestDeletePostRows(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x", PostCount: 2}
+	fan := &models.User{FullName: "Fan", Username: "fan", Email: "fan@example.com", Password: "x"}
+	mustCreate(t, db, author, fan)
+
+	cover := &models.Media{UserID: author.ID, Key: "media/cover.jpg", ContentType: "image/jpeg",
+		Renditions: map[string]models.MediaRendition{"thumb": {Key: "media/cover-thumb.jpg"}}}
+	second := &models.Media{UserID: author.ID, Key: "media/second.jpg", ContentType: "image/jpeg"}
+	mustCreate(t, db, cover, second)
+
+	post := &models.Post{Caption: "#sunset with @fan", ImageURL: "cover", UserID: author.ID, Status: "published", MediaID: &cover.ID}
+	other := &models.Post{Caption: "kept", ImageURL: "kept", UserID: author.ID, Status: "published"}
+	mustCreate(t, db, post, other)
+	if err := setPostHashtags(db, post, []string{"sunset"}); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &models.PostMedia{PostID: post.ID, Position: 0, MediaID: &cover.ID, URL: "cover"}
+	mustCreate(t, db, item)
+	mustCreate(t, db, &models.PostMedia{PostID: post.ID, Position: 1, MediaID: &second.ID, URL: "second"},
+		&models.PostMediaTag{PostMediaID: item.ID, UserID: fan.ID},
+		&models.Mention{MentionedID: fan.ID, MentionerID: author.ID, PostID: &post.ID, Username: "fan", Start: 15, End: 19})
+
+	clip := &models.AudioClip{UserID: fan.ID, Key: "audio/reply.m4a", URL: "reply", Format: "m4a", ContentType: "audio/mp4", Size: 1, DurationMs: 1}
+	mustCreate(t, db, clip)
+	comment := &models.Comment{Author: "fan", Content: "Nice @author", PostID: post.ID, UserID: fan.ID}
+	mustCreate(t, db, comment)
+	reply := &models.Comment{Author: "fan", Content: "Voice note", PostID: post.ID, UserID: fan.ID, ParentID: &comment.ID, Depth: 1, AudioID: &clip.ID}
+	kept := &models.Comment{Author: "fan", Content: "Kept", PostID: other.ID, UserID: fan.ID}
+	mustCreate(t, db, reply, kept)
+	mustCreate(t, db, &models.Mention{MentionedID: author.ID, MentionerID: fan.ID, CommentID: &comment.ID, Username: "author", Start: 5, End: 12},
+		&models.CommentLike{UserID: author.ID, CommentID: reply.ID, Emoji: models.DefaultReaction},
+		&models.CommentLike{UserID: author.ID, CommentID: kept.ID, Emoji: models.DefaultReaction},
+		&models.Like{UserID: fan.ID, PostID: post.ID, Emoji: models.DefaultReaction},
+		&models.Like{UserID: fan.ID, PostID: other.ID, Emoji: models.DefaultReaction},
+		&models.Save{UserID: fan.ID, PostID: post.ID},
+		&models.Notification{UserID: author.ID, ActorID: fan.ID, Type: models.NotificationMention, PostID: &post.ID, CommentID: &comment.ID})
+
+	var keys []string
+	if err := db.Transaction(func(tx *gorm.DB) (err error) {
+		keys, err = deletePostRows(tx, post)
+		return err
+	}); err != nil {
+		t.Fatalf("deletePostRows: %v", err)
+	}
+
+	sort.Strings(keys)
+	want := []string{"audio/reply.m4a", "media/cover-thumb.jpg", "media/cover.jpg", "media/second.jpg"}
+	if len(keys) != len(want) {
+		t.Fatalf("storage keys = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("storage keys = %v, want %v", keys, want)
+		}
+	}
+
+	// Everything attached to the post is gone, and nothing of the other post
+	remaining := []struct {
+		name  string
+		model interface{}
+		want  int64
+	}{
+		{"posts", &models.Post{}, 1},
+		{"post media", &models.PostMedia{}, 0},
+		{"post media tags", &models.PostMediaTag{}, 0},
+		{"media", &models.Media{}, 0},
+		{"comments", &models.Comment{}, 1},
+		{"comment likes", &models.CommentLike{}, 1},
+		{"audio clips", &models.AudioClip{}, 0},
+		{"mentions", &models.Mention{}, 0},
+		{"likes", &models.Like{}, 1},
+		{"saves", &models.Save{}, 0},
+		{"notifications", &models.Notification{}, 0},
+	}
+	for _, r := range remaining {
+		var count int64
+		if err := db.Model(r.model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != r.want {
+			t.Errorf("%d %s left, want %d", count, r.name, r.want)
+		}
+	}
+
+	var tagged int64
+	if err := db.Table("post_hashtags").Count(&tagged).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tagged != 0 {
+		t.Errorf("%d post hashtags left, want 0", tagged)
+	}
+
+	var user models.User
+	if err := db.First(&user, author.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.PostCount != 1 {
+		t.Errorf("author's PostCount = %d, want 1", user.PostCount)
+	}
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"pixi/models"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Carousel item limits
+const (
+	maxAltTextLength = 1000 // Characters
+	maxTagsPerItem   = 20
+)
+
+// withPostItems preloads a post's images and videos in display order, with
+// the users tagged in them
+func withPostItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Items.Media").Preload("Items.Tags.User", publicUserColumns)
+}
+
+// buildPostItems checks the images and videos requested for a post and
+// returns them as items ready to save, also pointing the deprecated
+// single-image fields at the cover. Each item needs a MediaID of the
+// author's that no other post uses; videos may still be processing, see
+// itemsProcessing. AltText and Tags, each with a UserID and X and Y between
+// 0 and 1, are optional. It responds and reports false when the request is
+// invalid.
+func buildPostItems(c *gin.Context, db *gorm.DB, post *models.Post, requested []models.PostMedia) ([]models.PostMedia, bool) {
+	if len(requested) == 0 || len(requested) > models.MaxPostMedia {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A post needs between 1 and %d images or videos", models.MaxPostMedia)})
+		return nil, false
+	}
+
+	items := make([]models.PostMedia, 0, len(requested))
+	seen := map[uint]bool{}
+	for position, request := range requested {
+		if request.MediaID == nil || seen[*request.MediaID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each item needs a MediaID of its own"})
+			return nil, false
+		}
+		seen[*request.MediaID] = true
+		if utf8.RuneCountInString(request.AltText) > maxAltTextLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "AltText is too long"})
+			return nil, false
+		}
+
+		media, err := findAttachableMedia(db, *request.MediaID, post.UserID, post.ID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Media not found, still uploading, failed processing or used in another post"})
+			return nil, false
+		}
+
+		tags, ok := buildMediaTags(c, db, post.UserID, request.Tags)
+		if !ok {
+			return nil, false
+		}
+
+		items = append(items, models.PostMedia{
+			Position: position,
+			MediaID:  &media.ID,
+			Media:    media,
+			URL:      media.URL,
+			AltText:  request.AltText,
+			Width:    media.Width,
+			Height:   media.Height,
+			Tags:     tags,
+		})
+	}
+
+	post.MediaID, post.Media, post.ImageURL = items[0].MediaID, items[0].Media, items[0].Media.PreviewURL()
+	return items, true
+}
+
+// itemsProcessing reports whether any of the items shows a video that is
+// still processing, which holds back publishing the post
+func itemsProcessing(items []models.PostMedia) bool {
+	for _, item := range items {
+		if item.Media != nil && item.Media.Status == models.MediaStatusProcessing {
+			return true
+		}
+	}
+	return false
+}
+
+// postItemsStatus sums up the media of a saved post: failed if any of it
+// failed processing, processing if any is still processing, otherwise ready
+func postItemsStatus(db *gorm.DB, postID uint) (string, error) {
+	var statuses []string
+	if err := db.Model(&models.Media{}).Where("id IN (SELECT media_id FROM post_media WHERE post_id = ?)", postID).
+		Distinct().Pluck("status", &statuses).Error; err != nil {
+		return "", err
+	}
+	status := models.MediaStatusReady
+	for _, s := range statuses {
+		switch s {
+		case models.MediaStatusFailed:
+			return s, nil
+		case models.MediaStatusProcessing, models.MediaStatusPending:
+			status = models.MediaStatusProcessing
+		}
+	}
+	return status, nil
+}
+
+// buildMediaTags checks the users tagged in one image: each at most once,
+// placed inside the image, and not blocked by or blocking the author
+func buildMediaTags(c *gin.Context, db *gorm.DB, authorID uint, requested []models.PostMediaTag) ([]models.PostMediaTag, bool) {
+	if len(requested) > maxTagsPerItem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("An image can tag at most %d users", maxTagsPerItem)})
+		return nil, false
+	}
+
+	tags := make([]models.PostMediaTag, 0, len(requested))
+	seen := map[uint]bool{}
+	for _, request := range requested {
+		if request.UserID == 0 || seen[request.UserID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each tag needs a UserID, tagged once per image"})
+			return nil, false
+		}
+		seen[request.UserID] = true
+		if request.X < 0 || request.X > 1 || request.Y < 0 || request.Y > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag X and Y must be between 0 and 1"})
+			return nil, false
+		}
+
+		var user models.User
+		if err := db.Scopes(notBlocked(authorID, "users.id")).Select("id", "username", "full_name", "profile_image").
+			First(&user, request.UserID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tagged user not found"})
+			return nil, false
+		}
+		tags = append(tags, models.PostMediaTag{UserID: user.ID, User: &user, X: request.X, Y: request.Y})
+	}
+	return tags, true
+}
+
+// postItemsOrCover returns the requested items, or for older clients that
+// send a single MediaID, one item showing it
+func postItemsOrCover(items []models.PostMedia, mediaID *uint) []models.PostMedia {
+	if len(items) == 0 && mediaID != nil {
+		return []models.PostMedia{{MediaID: mediaID}}
+	}
+	return items
+}
+
+// savePostItems stores the post's items and their tags
+func savePostItems(tx *gorm.DB, postID uint, items []models.PostMedia) error {
+	for i := range items {
+		items[i].PostID = postID
+		if err := tx.Omit("Media", "Tags").Create(&items[i]).Error; err != nil {
+			return err
+		}
+		for j := range items[i].Tags {
+			items[i].Tags[j].PostMediaID = items[i].ID
+			if err := tx.Omit("User").Create(&items[i].Tags[j]).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replacePostItems swaps the post's items for new ones, deleting the media
+// no longer shown. It returns the storage keys of that media, to delete once
+// the transaction commits.
+func replacePostItems(tx *gorm.DB, postID uint, items []models.PostMedia) ([]string, error) {
+	oldMediaIDs, err := deletePostItems(tx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := savePostItems(tx, postID, items); err != nil {
+		return nil, err
+	}
+
+	kept := map[uint]bool{}
+	for _, item := range items {
+		if item.MediaID != nil {
+			kept[*item.MediaID] = true
+		}
+	}
+	var dropped []uint
+	for _, id := range oldMediaIDs {
+		if !kept[id] {
+			dropped = append(dropped, id)
+		}
+	}
+	return deleteMediaRecords(tx, dropped)
+}
+
+// deletePostItems removes the post's items and their tags, returning the IDs
+// of the media they showed
+func deletePostItems(tx *gorm.DB, postID uint) ([]uint, error) {
+	var items []models.PostMedia
+	if err := tx.Select("id", "media_id").Where("post_id = ?", postID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	var itemIDs, mediaIDs []uint
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+		if item.MediaID != nil {
+			mediaIDs = append(mediaIDs, *item.MediaID)
+		}
+	}
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+	if err := tx.Where("post_media_id IN ?", itemIDs).Delete(&models.PostMediaTag{}).Error; err != nil {
+		return nil, err
+	}
+	return mediaIDs, tx.Where("id IN ?", itemIDs).Delete(&models.PostMedia{}).Error
+}
@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultReactionSet is used unless REACTION_EMOJIS lists the allowed emoji, comma separated
+const defaultReactionSet = "❤️,😂,😮,😢,😡,👍"
+
+// reactionSet returns the emoji users may react with. The default heart is
+// always allowed because the like endpoints give it.
+func reactionSet() []string {
+	set := []string{models.DefaultReaction}
+	for _, emoji := range strings.Split(utils.GetEnv("REACTION_EMOJIS", defaultReactionSet), ",") {
+		emoji = strings.TrimSpace(emoji)
+		if emoji != "" && emoji != models.DefaultReaction {
+			set = append(set, emoji)
+		}
+	}
+	return set
+}
+
+// validReaction reports whether emoji is in the configured reaction set
+func validReaction(emoji string) bool {
+	for _, allowed := range reactionSet() {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// reactionTarget is the post or comment a reaction is on: the reaction table,
+// its foreign key column and the row whose like_count it maintains
+type reactionTarget struct {
+	Table      string
+	Column     string
+	Counter    interface{}
+	ID         uint
+	HideCounts bool // The post's author hid its like counts from this viewer
+}
+
+// postReactions targets the reactions on a post
+func postReactions(id uint) reactionTarget {
+	return reactionTarget{Table: "likes", Column: "post_id", Counter: &models.Post{}, ID: id}
+}
+
+// commentReactions targets the reactions on a comment or reply
+func commentReactions(id uint) reactionTarget {
+	return reactionTarget{Table: "comment_likes", Column: "comment_id", Counter: &models.Comment{}, ID: id}
+}
+
+// add records a new reaction, reporting false when the user already reacted
+func (target reactionTarget) add(tx *gorm.DB, userID uint, emoji string) (bool, error) {
+	result := tx.Table(target.Table).Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{
+		"user_id":     userID,
+		target.Column: target.ID,
+		"emoji":       emoji,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, adjustCounter(tx, target.Counter, target.ID, "like_count", 1)
+}
+
+// set adds a reaction or changes the user's existing one, reporting whether it was added
+func (target reactionTarget) set(tx *gorm.DB, userID uint, emoji string) (bool, error) {
+	result := tx.Table(target.Table).Where("user_id = ? AND "+target.Column+" = ?", userID, target.ID).Update("emoji", emoji)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return false, result.Error
+	}
+	return target.add(tx, userID, emoji)
+}
+
+// remove deletes the user's reaction, reporting false when there was none
+func (target reactionTarget) remove(tx *gorm.DB, userID uint) (bool, error) {
+	result := tx.Table(target.Table).Where("user_id = ? AND "+target.Column+" = ?", userID, target.ID).Delete(nil)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, adjustCounter(tx, target.Counter, target.ID, "like_count", -1)
+}
+
+// reactionSummary holds the reactions on a set of posts or comments
+type reactionSummary struct {
+	Counts map[uint]map[string]int64
+	Mine   map[uint]string
+}
+
+// loadReactionSummary counts the reactions by emoji on every row in ids and
+// finds the viewer's own, in two queries
+func loadReactionSummary(db *gorm.DB, table, column string, viewer uint, ids []uint) (reactionSummary, error) {
+	summary := reactionSummary{Counts: map[uint]map[string]int64{}, Mine: map[uint]string{}}
+	if len(ids) == 0 {
+		return summary, nil
+	}
+
+	var counts []struct {
+		TargetID uint
+		Emoji    string
+		Total    int64
+	}
+	if err := db.Table(table).Select(column+" AS target_id, emoji, COUNT(*) AS total").
+		Where(column+" IN ?", ids).Group(column + ", emoji").Scan(&counts).Error; err != nil {
+		return summary, err
+	}
+	for _, count := range counts {
+		if summary.Counts[count.TargetID] == nil {
+			summary.Counts[count.TargetID] = map[string]int64{}
+		}
+		summary.Counts[count.TargetID][count.Emoji] = count.Total
+	}
+
+	if viewer != 0 {
+		var mine []struct {
+			TargetID uint
+			Emoji    string
+		}
+		if err := db.Table(table).Select(column+" AS target_id, emoji").
+			Where("user_id = ? AND "+column+" IN ?", viewer, ids).Scan(&mine).Error; err != nil {
+			return summary, err
+		}
+		for _, reaction := range mine {
+			summary.Mine[reaction.TargetID] = reaction.Emoji
+		}
+	}
+	return summary, nil
+}
+
+// reactionCounts returns the counts for one row, never nil so clients always get an object
+func (summary reactionSummary) reactionCounts(id uint) map[string]int64 {
+	if counts, ok := summary.Counts[id]; ok {
+		return counts
+	}
+	return map[string]int64{}
+}
+
+// attachPostReactions fills in the reaction counts, the viewer's reaction and
+// whether they reacted on each post. Call it before applyLikeVisibility, which
+// hides the counts.
+func attachPostReactions(db *gorm.DB, viewer uint, posts []models.Post) error {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	summary, err := loadReactionSummary(db, "likes", "post_id", viewer, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Reactions = summary.reactionCounts(posts[i].ID)
+		posts[i].MyReaction = summary.Mine[posts[i].ID]
+		posts[i].LikedByMe = posts[i].MyReaction != ""
+	}
+	return nil
+}
+
+// attachCommentReactions fills in the reaction counts and the viewer's
+// reaction on comments, including every reply loaded below them
+func attachCommentReactions(db *gorm.DB, viewer uint, comments []models.Comment) error {
+	var ids []uint
+	var collect func(comments []models.Comment)
+	collect = func(comments []models.Comment) {
+		for i := range comments {
+			ids = append(ids, comments[i].ID)
+			collect(comments[i].Replies)
+		}
+	}
+	collect(comments)
+
+	summary, err := loadReactionSummary(db, "comment_likes", "comment_id", viewer, ids)
+	if err != nil {
+		return err
+	}
+
+	var fill func(comments []models.Comment)
+	fill = func(comments []models.Comment) {
+		for i := range comments {
+			comments[i].Reactions = summary.reactionCounts(comments[i].ID)
+			comments[i].MyReaction = summary.Mine[comments[i].ID]
+			comments[i].LikedByMe = comments[i].MyReaction != ""
+			fill(comments[i].Replies)
+		}
+	}
+	fill(comments)
+	return nil
+}
+
+// GetReactionSet lists the emoji users may react with
+func GetReactionSet(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"reactions": reactionSet(), "default": models.DefaultReaction})
+}
+
+// SetPostReaction reacts to a post as the logged in user, replacing any earlier reaction
+func SetPostReaction(c *gin.Context) {
+	db := config.GetDB()
+
+	var post models.Post
+	if !findVisiblePost(c, db, c.Param("id"), &post) {
+		return
+	}
+	target := postReactions(post.ID)
+	target.HideCounts = post.HideLikeCounts && post.UserID != viewerID(c)
+	setReaction(c, db, target, post.LikeCount)
+}
+
+// DeletePostReaction removes the logged in user's reaction from a post
+func DeletePostReaction(c *gin.Context) {
+	db := config.GetDB()
+
+	var post models.Post
+	if err := db.First(&post, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	target := postReactions(post.ID)
+	target.HideCounts = post.HideLikeCounts && post.UserID != viewerID(c)
+	deleteReaction(c, db, target, post.LikeCount)
+}
+
+// SetCommentReaction reacts to a comment or reply as the logged in user, replacing any earlier reaction
+func SetCommentReaction(c *gin.Context) {
+	db := config.GetDB()
+
+	comment, ok := findVisibleComment(c, db, c.Param("commentID"))
+	if !ok {
+		return
+	}
+	setReaction(c, db, commentReactions(comment.ID), comment.LikeCount)
+}
+
+// DeleteCommentReaction removes the logged in user's reaction from a comment or reply
+func DeleteCommentReaction(c *gin.Context) {
+	db := config.GetDB()
+
+	var comment models.Comment
+	if err := db.First(&comment, c.Param("commentID")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	deleteReaction(c, db, commentReactions(comment.ID), comment.LikeCount)
+}
+
+// setReaction validates the requested emoji and stores it on target, responding with the new counts
+func setReaction(c *gin.Context, db *gorm.DB, target reactionTarget, likeCount int64) {
+	var input struct {
+		Emoji string `json:"Emoji"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if !validReaction(input.Emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Emoji must be one of the allowed reactions", "reactions": reactionSet()})
+		return
+	}
+
+	// Add or change the reaction and keep the count in step
+	added := false
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		added, err = target.set(tx, viewerID(c), input.Emoji)
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving reaction", "details": err.Error()})
+		return
+	}
+	if added {
+		likeCount++
+	}
+
+	respondWithReactions(c, db, target, likeCount, "Reaction saved successfully")
+}
+
+// deleteReaction removes the viewer's reaction from target, responding with the new counts
+func deleteReaction(c *gin.Context, db *gorm.DB, target reactionTarget, likeCount int64) {
+	removed := false
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		removed, err = target.remove(tx, viewerID(c))
+		return err
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reaction", "details": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reaction not found"})
+		return
+	}
+	if likeCount > 0 {
+		likeCount--
+	}
+
+	respondWithReactions(c, db, target, likeCount, "Reaction deleted successfully")
+}
+
+// respondWithReactions writes the target's counts after a change
+func respondWithReactions(c *gin.Context, db *gorm.DB, target reactionTarget, likeCount int64, message string) {
+	viewer := viewerID(c)
+	summary, err := loadReactionSummary(db, target.Table, target.Column, viewer, []uint{target.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load reactions", "details": err.Error()})
+		return
+	}
+
+	reactions := summary.reactionCounts(target.ID)
+	if target.HideCounts {
+		likeCount, reactions = 0, map[string]int64{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     message,
+		"like_count":  likeCount,
+		"reactions":   reactions,
+		"my_reaction": summary.Mine[target.ID],
+	})
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxRelationshipBatch caps how many users one relationships request may ask about
+const maxRelationshipBatch = 100
+
+// GetRelationships returns the logged in user's relationship to each user in
+// the comma separated ids query parameter, in the order requested
+func GetRelationships(c *gin.Context) {
+	ids, err := parseIDList(c.Query("ids"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must be a comma separated list of user IDs", "details": err.Error()})
+		return
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must be a comma separated list of user IDs"})
+		return
+	}
+	if len(ids) > maxRelationshipBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many user IDs", "max": maxRelationshipBatch})
+		return
+	}
+
+	relationships, err := relationshipsFor(config.GetDB(), viewerID(c), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve relationships", "details": err.Error()})
+		return
+	}
+
+	ordered := make([]*models.Relationship, len(ids))
+	for i, id := range ids {
+		ordered[i] = relationships[id]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"relationships": ordered})
+}
+
+// GetRelationship returns the logged in user's relationship to a single user
+func GetRelationship(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	relationships, err := relationshipsFor(config.GetDB(), viewerID(c), []uint{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve relationship", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, relationships[id])
+}
+
+// relationshipsFor computes the viewer's relationship to every user in ids
+// with one query per relationship kind
+func relationshipsFor(db *gorm.DB, viewer uint, ids []uint) (map[uint]*models.Relationship, error) {
+	relationships := make(map[uint]*models.Relationship, len(ids))
+	for _, id := range ids {
+		relationships[id] = &models.Relationship{UserID: id}
+	}
+	if viewer == 0 || len(ids) == 0 {
+		return relationships, nil
+	}
+
+	// Follows and requests from the viewer
+	var outgoing []models.Follow
+	if err := db.Where("follower_id = ? AND following_id IN ?", viewer, ids).Find(&outgoing).Error; err != nil {
+		return nil, err
+	}
+	for _, follow := range outgoing {
+		relationships[follow.FollowingID].Following = follow.Status == models.FollowStatusAccepted
+		relationships[follow.FollowingID].Requested = follow.Status == models.FollowStatusPending
+	}
+
+	// Follows and requests to the viewer
+	var incoming []models.Follow
+	if err := db.Where("following_id = ? AND follower_id IN ?", viewer, ids).Find(&incoming).Error; err != nil {
+		return nil, err
+	}
+	for _, follow := range incoming {
+		relationships[follow.FollowerID].FollowedBy = follow.Status == models.FollowStatusAccepted
+		relationships[follow.FollowerID].IncomingRequest = follow.Status == models.FollowStatusPending
+	}
+
+	// Blocks placed by the viewer
+	var blockedIDs []uint
+	if err := db.Model(&models.Block{}).Where("blocker_id = ? AND blocked_id IN ?", viewer, ids).Pluck("blocked_id", &blockedIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range blockedIDs {
+		relationships[id].Blocked = true
+	}
+
+	// Mutes placed by the viewer
+	var mutedIDs []uint
+	if err := db.Model(&models.Mute{}).Where("muter_id = ? AND muted_id IN ?", viewer, ids).Pluck("muted_id", &mutedIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range mutedIDs {
+		relationships[id].Muted = true
+	}
+
+	// The viewer's close friends
+	var closeFriendIDs []uint
+	if err := db.Model(&models.CloseFriend{}).Where("user_id = ? AND friend_id IN ?", viewer, ids).Pluck("friend_id", &closeFriendIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range closeFriendIDs {
+		relationships[id].CloseFriend = true
+	}
+
+	return relationships, nil
+}
+
+// parseIDList parses a comma separated list of IDs, dropping duplicates. Any
+// part that is not a positive integer is an error.
+func parseIDList(raw string) ([]uint, error) {
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid user ID %q", part)
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids, nil
+}
@@ -9,7 +9,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetRepliesByPostID retrieves all replies associated with a given comment ID
+// The reply endpoints predate threaded comments. Replies are now comments
+// with a ParentID, so these handlers map the old routes onto the comment
+// ones for clients that still use them. Replies come back in the comment
+// shape, with ParentID in place of CommentID, and updates and deletes answer
+// as the comment routes do.
+
+// GetRepliesByPostID retrieves every reply on a post, oldest first.
+//
+// Deprecated: page through each comment's replies with GET /comment/:commentID/replies.
 func GetRepliesByPostID(c *gin.Context) {
 	postID, err := strconv.Atoi(c.Param("postID"))
 	if err != nil || postID <= 0 {
@@ -18,21 +26,35 @@ func GetRepliesByPostID(c *gin.Context) {
 	}
 
 	db := config.GetDB()
-	var replies []models.Reply
+	viewer := viewerID(c)
 
-	// Retrieve replies by post ID (assuming there's a `post_id` field)
-	if err := db.Preload("Author").Where("post_id = ?", postID).Find(&replies).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies"})
+	// Replies on posts the viewer cannot see stay hidden
+	var post models.Post
+	if !findVisiblePost(c, db, postID, &post) {
+		return
+	}
+
+	// Retrieve every comment on the post that answers another one
+	replies := []models.Comment{}
+	if err := db.Preload("User").Preload("Mentions").Preload("Audio").Scopes(visibleComments(viewer)).
+		Where("post_id = ? AND parent_id IS NOT NULL", post.ID).Order("id").Find(&replies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies", "details": err.Error()})
+		return
+	}
+	if err := attachCommentReactions(db, viewer, replies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve replies", "details": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"replies": replies})
 }
 
-// CreateReply handles the creation of a new reply
+// CreateReply saves a reply to the comment with the old CommentID, as a
+// comment threaded under it. Replies without a CommentID become top-level
+// comments.
+//
+// Deprecated: post a comment with a ParentID to /posts/:postID/comments.
 func CreateReply(c *gin.Context) {
-	var newReply models.Reply
-
 	// Get userID from the context
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -40,107 +62,59 @@ func CreateReply(c *gin.Context) {
 		return
 	}
 
-	// Assign the UserID from the context to the new post
-	newReply.UserID = userID.(uint)
-
-	// Bind the request body to newReply object
-	if err := c.ShouldBindJSON(&newReply); err != nil {
+	// Bind the fields of the old reply body; the rest is set server-side
+	var input struct {
+		PostID    uint
+		CommentID *uint
+		Content   string
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
 		return
 	}
 
 	// Validate required fields
-	if newReply.PostID == 0 || newReply.UserID == 0 || newReply.AuthorID == 0 || newReply.Content == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID, User ID, Reply User ID, Comment ID, and Content are required"})
+	if input.PostID == 0 || input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID and Content are required"})
 		return
 	}
 
-	// Get the DB connection
+	// Comments carry their commenter's username, which old clients never sent
 	db := config.GetDB()
-
-	// Ensure the post, user, and comment exist before creating a reply
-	var post models.Post
 	var user models.User
-	var comment models.Comment
-
-	if err := db.First(&post, newReply.PostID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		return
-	}
-
-	if err := db.First(&user, newReply.UserID).Error; err != nil {
+	if err := db.Select("id", "username").First(&user, userID.(uint)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	if err := db.First(&comment, newReply.CommentID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
-		return
+	reply := models.Comment{
+		PostID:   input.PostID,
+		ParentID: input.CommentID,
+		UserID:   user.ID,
+		Author:   user.Username,
+		Content:  input.Content,
 	}
-
-	// Save the new reply to the database
-	if err := db.Create(&newReply).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving reply", "details": err.Error()})
+	if !saveComment(c, &reply) {
 		return
 	}
 
 	// Respond with the created reply
-	c.JSON(http.StatusCreated, gin.H{"message": "Reply created successfully", "reply": newReply})
+	c.JSON(http.StatusCreated, gin.H{"message": "Reply created successfully", "reply": reply})
 }
 
-// UpdateReply updates the reply
+// UpdateReply updates a reply's content; only its commenter may edit it.
+//
+// Deprecated: use PATCH /comment/:commentID.
 func UpdateReply(c *gin.Context) {
-	db := config.GetDB()
-	replyID := c.Param("id")
-
-	// Bind the input JSON data to a map to handle specific fields
-	var updatedReply models.Reply
-
-	// Bind incoming JSON request data to updatedReply object
-	if err := c.ShouldBindJSON(&updatedReply); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
-		return
-	}
-
-	// Find the reply by ID
-	var existingReply models.Reply
-	if err := db.First(&existingReply, replyID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reply not found"})
-		return
-	}
-
-	// Update the reply with the new data
-	if updatedReply.Content != "" {
-		existingReply.Content = updatedReply.Content
-	}
-
-	// Save the updated reply to the database
-	if err := db.Save(&existingReply).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reply", "details": err.Error()})
-		return
-	}
-
-	// Respond with the updated reply
-	c.JSON(http.StatusOK, gin.H{"message": "Reply updated successfully", "reply": existingReply})
+	c.Params = append(c.Params, gin.Param{Key: "commentID", Value: c.Param("replyID")})
+	UpdateComment(c)
 }
 
-// Delete a reply
+// DeleteReply deletes a reply with its own replies, as its commenter or the
+// post's author.
+//
+// Deprecated: use DELETE /comment/:commentID.
 func DeleteReply(c *gin.Context) {
-	db := config.GetDB()
-	var reply models.Reply
-	replyID := c.Param("id")
-
-	// Find the reply by ID
-	if err := db.First(&reply, replyID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Reply not found"})
-		return
-	}
-
-	// Delete the reply
-	if err := db.Delete(&reply).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reply", "details": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Reply deleted successfully"})
+	c.Params = append(c.Params, gin.Param{Key: "commentID", Value: c.Param("replyID")})
+	DeleteComment(c)
 }
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreateSave handles the creation of a new save
@@ -40,20 +41,24 @@ func CreateSave(c *gin.Context) {
 
 	// Ensure the post exists
 	var post models.Post
-	if err := db.First(&post, newSave.PostID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	if !findVisiblePost(c, db, newSave.PostID, &post) {
 		return
 	}
 
-	// Prevent duplicate likes
-	var existingSave models.Like
+	// Prevent duplicate saves
+	var existingSave models.Save
 	if err := db.Where("user_id = ? AND post_id = ?", newSave.UserID, newSave.PostID).First(&existingSave).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Save already exists"})
 		return
 	}
 
-	// Save the new like to the database
-	if err := db.Create(&newSave).Error; err != nil {
+	// Save the new save and bump the post's save count together
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newSave).Error; err != nil {
+			return err
+		}
+		return adjustCounter(tx, &models.Post{}, newSave.PostID, "save_count", 1)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving post", "details": err.Error()})
 		return
 	}
@@ -97,8 +102,13 @@ func DeleteSave(c *gin.Context) {
 		return
 	}
 
-	// Delete the save
-	if err := db.Delete(&save).Error; err != nil {
+	// Delete the save and drop the post's save count together
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&save).Error; err != nil {
+			return err
+		}
+		return adjustCounter(tx, &models.Post{}, save.PostID, "save_count", -1)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved post", "details": err.Error()})
 		return
 	}
@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"pixi/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Search result kinds accepted by the type query parameter
+const (
+	searchTypeUsers    = "users"
+	searchTypePosts    = "posts"
+	searchTypeHashtags = "hashtags"
+	maxSearchLimit     = 50
+)
+
+// Search finds users, posts and hashtags matching the q query parameter. With
+// type set it searches one kind and pages through it with offset and limit;
+// without it, it returns the top results of every kind.
+func Search(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	query := strings.TrimSpace(c.Query("q"))
+	terms := utils.SearchTerms(query)
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain at least one letter or digit"})
+		return
+	}
+
+	// Retrieve query parameters for pagination
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	kind := c.Query("type")
+	switch kind {
+	case "":
+		// Top results of every kind; paging needs a type
+		offset = 0
+	case searchTypeUsers, searchTypePosts, searchTypeHashtags:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be users, posts or hashtags"})
+		return
+	}
+
+	response := gin.H{}
+	found := 0
+
+	if kind == "" || kind == searchTypeUsers {
+		users, err := searchUsers(db, viewer, query, terms, offset, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users", "details": err.Error()})
+			return
+		}
+		response["users"], found = users, len(users)
+	}
+
+	if kind == "" || kind == searchTypePosts {
+		posts, err := searchPosts(db, viewer, terms, offset, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts", "details": err.Error()})
+			return
+		}
+		if err := attachPostReactions(db, viewer, posts); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts", "details": err.Error()})
+			return
+		}
+		for i := range posts {
+			applyLikeVisibility(&posts[i], viewer)
+		}
+		response["posts"], found = posts, len(posts)
+	}
+
+	if kind == "" || kind == searchTypeHashtags {
+		hashtags, err := searchHashtags(db, terms, offset, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search hashtags", "details": err.Error()})
+			return
+		}
+		response["hashtags"], found = hashtags, len(hashtags)
+	}
+
+	// A full page means there may be more
+	if kind != "" && found == limit {
+		response["next_offset"] = offset + limit
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// searchUsers matches usernames and full names, hiding users on the other side of a block
+func searchUsers(db *gorm.DB, viewer uint, query string, terms []string, offset, limit int) ([]models.User, error) {
+	users := []models.User{}
+	search := db.Model(&models.User{}).Scopes(notBlocked(viewer, "users.id"))
+
+	switch {
+	case db.Dialector.Name() == "postgres":
+		// Prefix full-text matches, plus trigram matches to forgive typos
+		document := "to_tsvector('simple', users.username || ' ' || users.full_name)"
+		tsQuery := prefixTSQuery(terms)
+		search = search.
+			Select("users.*, ts_rank("+document+", to_tsquery('simple', ?)) + GREATEST(similarity(users.username, ?), similarity(users.full_name, ?)) AS search_rank", tsQuery, query, query).
+			Where(document+" @@ to_tsquery('simple', ?) OR users.username % ? OR users.full_name % ?", tsQuery, query, query).
+			Order("search_rank DESC")
+	case hasFTSTable(db, "users_fts"):
+		search = search.Joins("JOIN users_fts ON users_fts.rowid = users.id").
+			Where("users_fts MATCH ?", ftsMatch(terms)).
+			Order("bm25(users_fts)")
+	default:
+		for _, term := range terms {
+			search = search.Where("(LOWER(users.username) LIKE ? ESCAPE '\\' OR LOWER(users.full_name) LIKE ? ESCAPE '\\')", utils.LikeContains(term), utils.LikeContains(term))
+		}
+		search = search.Order("users.follower_count DESC")
+	}
+
+	err := search.Order("users.id").Offset(offset).Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// searchPosts matches captions and descriptions of published posts the viewer may see
+func searchPosts(db *gorm.DB, viewer uint, terms []string, offset, limit int) ([]models.Post, error) {
+	posts := []models.Post{}
+	search := db.Model(&models.Post{}).Preload("User").Preload("Hashtags").Preload("Mentions").Scopes(withPostItems).
+		Scopes(visiblePosts(viewer), notMuted(viewer, "posts.user_id", "mute_posts")).
+		Where("posts.status = ?", "published")
+
+	switch {
+	case db.Dialector.Name() == "postgres":
+		document := "to_tsvector('simple', posts.caption || ' ' || COALESCE(posts.description, ''))"
+		tsQuery := prefixTSQuery(terms)
+		search = search.
+			Select("posts.*, ts_rank("+document+", to_tsquery('simple', ?)) AS search_rank", tsQuery).
+			Where(document+" @@ to_tsquery('simple', ?)", tsQuery).
+			Order("search_rank DESC")
+	case hasFTSTable(db, "posts_fts"):
+		search = search.Joins("JOIN posts_fts ON posts_fts.rowid = posts.id").
+			Where("posts_fts MATCH ?", ftsMatch(terms)).
+			Order("bm25(posts_fts)")
+	default:
+		for _, term := range terms {
+			search = search.Where("(LOWER(posts.caption) LIKE ? ESCAPE '\\' OR LOWER(posts.description) LIKE ? ESCAPE '\\')", utils.LikeContains(term), utils.LikeContains(term))
+		}
+		search = search.Order("posts.like_count DESC")
+	}
+
+	err := search.Order("posts.id DESC").Offset(offset).Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// searchHashtags matches tag names, preferring prefix matches and popular tags.
+// The terms are joined because hashtags cannot contain spaces.
+func searchHashtags(db *gorm.DB, terms []string, offset, limit int) ([]models.Hashtag, error) {
+	hashtags := []models.Hashtag{}
+	name := strings.Join(terms, "")
+	prefixMatch := "CASE WHEN hashtags.name LIKE ? ESCAPE '\\' THEN 1 ELSE 0 END AS prefix_match"
+	search := db.Model(&models.Hashtag{})
+
+	if db.Dialector.Name() == "postgres" {
+		search = search.
+			Select("hashtags.*, "+prefixMatch+", similarity(hashtags.name, ?) AS search_rank", utils.LikePrefix(name), name).
+			Where("hashtags.name LIKE ? ESCAPE '\\' OR hashtags.name % ?", utils.LikePrefix(name), name).
+			Order("prefix_match DESC").Order("search_rank DESC")
+	} else {
+		search = search.
+			Select("hashtags.*, "+prefixMatch, utils.LikePrefix(name)).
+			Where("hashtags.name LIKE ? ESCAPE '\\'", utils.LikeContains(name)).
+			Order("prefix_match DESC")
+	}
+
+	err := search.Order("hashtags.post_count DESC").Order("hashtags.id").Offset(offset).Limit(limit).Find(&hashtags).Error
+	return hashtags, err
+}
+
+// prefixTSQuery builds a Postgres tsquery matching every term as a prefix.
+// Terms come from utils.SearchTerms so they hold no tsquery operators.
+func prefixTSQuery(terms []string) string {
+	return strings.Join(terms, ":* & ") + ":*"
+}
+
+// ftsMatch builds an FTS5 query matching every term as a prefix
+func ftsMatch(terms []string) string {
+	return `"` + strings.Join(terms, `"* "`) + `"*`
+}
+
+// hasFTSTable reports whether the SQLite full-text table was created by models.PrepareSearch
+func hasFTSTable(db *gorm.DB, table string) bool {
+	return db.Dialector.Name() == "sqlite" && db.Migrator().HasTable(table)
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"pixi/models"
+	"pixi/utils"
+	"slices"
+	"testing"
+)
+
+func TestSearchUsersLikeFallback(t *testing.T) {
+	db := newTestDB(t)
+
+	viewer := &models.User{FullName: "Viewer", Username: "viewer", Email: "viewer@example.com", Password: "x"}
+	alice := &models.User{FullName: "Alice Smith", Username: "alice_smith", Email: "alice@example.com", Password: "x", FollowerCount: 5}
+	lookalike := &models.User{FullName: "Someone Else", Username: "alicexsmith", Email: "lookalike@example.com", Password: "x", FollowerCount: 1}
+	bob := &models.User{FullName: "Bob Alison", Username: "bob", Email: "bob@example.com", Password: "x", FollowerCount: 10}
+	blocker := &models.User{FullName: "Alice Blocker", Username: "alice_b", Email: "blocker@example.com", Password: "x", FollowerCount: 50}
+	mustCreate(t, db, viewer, alice, lookalike, bob, blocker)
+	mustCreate(t, db, &models.Block{BlockerID: blocker.ID, BlockedID: viewer.ID})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"ALI", []string{"bob", "alice_smith", "alicexsmith"}},
+		{"alice smith", []string{"alice_smith", "alicexsmith"}},
+		{"alice_smith", []string{"alice_smith"}},
+		{"bob jones", []string{}},
+	}
+	for _, tt := range tests {
+		users, err := searchUsers(db, viewer.ID, tt.query, utils.SearchTerms(tt.query), 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, user := range users {
+			got = append(got, user.Username)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("searchUsers(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchPostsLikeFallback(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	viewer := &models.User{FullName: "Viewer", Username: "viewer", Email: "viewer@example.com", Password: "x"}
+	mustCreate(t, db, author, viewer)
+	mustCreate(t, db,
+		&models.Post{Caption: "Sunset at the beach", ImageURL: "post", UserID: author.ID, Status: "published", LikeCount: 1},
+		&models.Post{Caption: "Beach day", Description: "Sunny", ImageURL: "post", UserID: author.ID, Status: "published", LikeCount: 5},
+		&models.Post{Caption: "Beach tomorrow", ImageURL: "post", UserID: author.ID, Status: "scheduled", LikeCount: 9},
+		&models.Post{Caption: "Beach with friends", ImageURL: "post", UserID: author.ID, Status: "published", LikeCount: 9,
+			Audience: models.AudienceFollowers},
+	)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"BEACH", []string{"Beach day", "Sunset at the beach"}},
+		{"sun", []string{"Beach day", "Sunset at the beach"}},
+		{"sunset beach", []string{"Sunset at the beach"}},
+		{"mountain", []string{}},
+	}
+	for _, tt := range tests {
+		posts, err := searchPosts(db, viewer.ID, utils.SearchTerms(tt.query), 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, post := range posts {
+			got = append(got, post.Caption)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("searchPosts(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Suggestion scoring weights and limits
+const (
+	mutualFollowWeight       = 3   // Score per followed account that also follows the candidate
+	sharedLikeWeight         = 1   // Score per post both the viewer and the candidate liked
+	suggestionCandidateLimit = 200 // Candidates read from each signal before ranking
+	maxSuggestionLimit       = 50
+)
+
+// Reasons explaining why an account was suggested
+const (
+	suggestionReasonMutualFollows = "followed_by_people_you_follow"
+	suggestionReasonSharedLikes   = "likes_posts_you_like"
+	suggestionReasonPopular       = "popular"
+)
+
+// suggestion is a single recommended account in the /suggestions response
+type suggestion struct {
+	User        models.User
+	Score       int64
+	MutualCount int64
+	SharedLikes int64
+	Reasons     []string
+}
+
+// suggestionCandidate is a raw row from one of the suggestion signals
+type suggestionCandidate struct {
+	UserID uint
+	Total  int64
+}
+
+// GetSuggestions recommends accounts for the logged in user to follow
+func GetSuggestions(c *gin.Context) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxSuggestionLimit {
+		limit = maxSuggestionLimit
+	}
+
+	// Friends of friends: accounts followed by the accounts the viewer follows
+	var mutuals []suggestionCandidate
+	if err := db.Table("follows AS f1").
+		Select("f2.following_id AS user_id, COUNT(*) AS total").
+		Joins("JOIN follows AS f2 ON f2.follower_id = f1.following_id AND f2.status = ?", models.FollowStatusAccepted).
+		Where("f1.follower_id = ? AND f1.status = ?", viewer, models.FollowStatusAccepted).
+		Scopes(suggestable(viewer, "f2.following_id")).
+		Group("f2.following_id").Order("total DESC").Limit(suggestionCandidateLimit).
+		Scan(&mutuals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute suggestions", "details": err.Error()})
+		return
+	}
+
+	// Shared taste: accounts that liked the same posts as the viewer
+	var sharedLikes []suggestionCandidate
+	if err := db.Table("likes AS l1").
+		Select("l2.user_id AS user_id, COUNT(DISTINCT l2.post_id) AS total").
+		Joins("JOIN likes AS l2 ON l2.post_id = l1.post_id").
+		Where("l1.user_id = ?", viewer).
+		Scopes(suggestable(viewer, "l2.user_id")).
+		Group("l2.user_id").Order("total DESC").Limit(suggestionCandidateLimit).
+		Scan(&sharedLikes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute suggestions", "details": err.Error()})
+		return
+	}
+
+	// Combine the signals into one score per account
+	scored := map[uint]*suggestion{}
+	candidateFor := func(userID uint) *suggestion {
+		if scored[userID] == nil {
+			scored[userID] = &suggestion{User: models.User{ID: userID}, Reasons: []string{}}
+		}
+		return scored[userID]
+	}
+	for _, candidate := range mutuals {
+		s := candidateFor(candidate.UserID)
+		s.MutualCount = candidate.Total
+		s.Score += candidate.Total * mutualFollowWeight
+		s.Reasons = append(s.Reasons, suggestionReasonMutualFollows)
+	}
+	for _, candidate := range sharedLikes {
+		s := candidateFor(candidate.UserID)
+		s.SharedLikes = candidate.Total
+		s.Score += candidate.Total * sharedLikeWeight
+		s.Reasons = append(s.Reasons, suggestionReasonSharedLikes)
+	}
+
+	ranked := make([]*suggestion, 0, len(scored))
+	for _, s := range scored {
+		ranked = append(ranked, s)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].User.ID < ranked[j].User.ID
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	// Cold start: top up with the most followed accounts
+	if len(ranked) < limit {
+		query := db.Model(&models.User{}).Select("id AS user_id, follower_count AS total").
+			Scopes(suggestable(viewer, "users.id"))
+		if len(ranked) > 0 {
+			chosen := make([]uint, len(ranked))
+			for i, s := range ranked {
+				chosen[i] = s.User.ID
+			}
+			query = query.Where("users.id NOT IN ?", chosen)
+		}
+
+		var popular []suggestionCandidate
+		if err := query.Order("follower_count DESC").Order("id").Limit(limit - len(ranked)).Scan(&popular).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute suggestions", "details": err.Error()})
+			return
+		}
+		for _, candidate := range popular {
+			ranked = append(ranked, &suggestion{User: models.User{ID: candidate.UserID}, Reasons: []string{suggestionReasonPopular}})
+		}
+	}
+
+	// Load the suggested accounts' public profiles
+	ids := make([]uint, len(ranked))
+	for i, s := range ranked {
+		ids[i] = s.User.ID
+	}
+	var users []models.User
+	if len(ids) > 0 {
+		if err := db.Scopes(publicUserColumns).Where("id IN ?", ids).Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load suggested users", "details": err.Error()})
+			return
+		}
+	}
+	usersByID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	suggestions := make([]suggestion, 0, len(ranked))
+	for _, s := range ranked {
+		if user, ok := usersByID[s.User.ID]; ok {
+			s.User = user
+			suggestions = append(suggestions, *s)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// DismissSuggestion stops a user from being suggested to the logged in user again
+func DismissSuggestion(c *gin.Context) {
+	db := config.GetDB()
+
+	dismissedID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	// Ensure the dismissed user exists
+	var dismissed models.User
+	if err := db.First(&dismissed, dismissedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Dismissing twice is harmless
+	dismissal := models.SuggestionDismissal{UserID: viewerID(c), DismissedID: dismissedID}
+	if err := db.Where(dismissal).FirstOrCreate(&dismissal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss suggestion", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suggestion dismissed successfully"})
+}
+
+// suggestable scopes a query to accounts that may be suggested to the viewer:
+// not themselves, not already followed or requested, not dismissed and not blocked
+func suggestable(viewer uint, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(notBlocked(viewer, column)).Where(
+			column+" <> ? AND "+
+				column+" NOT IN (SELECT following_id FROM follows WHERE follower_id = ?) AND "+
+				column+" NOT IN (SELECT dismissed_id FROM suggestion_dismissals WHERE user_id = ?)",
+			viewer, viewer, viewer,
+		)
+	}
+}
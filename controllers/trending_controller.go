@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"pixi/config"
+	"pixi/models"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Trending hashtag tuning
+const (
+	trendingWindow   = 3 * time.Hour      // Recent usage that counts towards trending, in whole hours
+	trendingBaseline = 7 * 24 * time.Hour // History before the window used as the baseline rate
+	trendingMinUses  = 3                  // Tags used less than this in the window never trend
+	trendingKeep     = 100                // Trending rows stored per computation
+	maxTrendingLimit = 50
+	trendingMaxAge   = time.Hour   // Lists older than this are recomputed on the next read
+	trendingRecheck  = time.Minute // How often one instance looks at the list's age
+)
+
+// trendingChecked throttles the age check in refreshStaleTrending per instance
+var trendingChecked struct {
+	sync.Mutex
+	at time.Time
+}
+
+// GetTrendingHashtags returns the top trending hashtags from the latest computation
+func GetTrendingHashtags(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxTrendingLimit {
+		limit = maxTrendingLimit
+	}
+
+	// Recompute a stale list first, as the daily cron alone would leave it hours old
+	db := config.GetDB()
+	if err := refreshStaleTrending(db, time.Now().UTC()); err != nil {
+		log.Println("Error refreshing trending hashtags:", err)
+	}
+
+	var trending []models.TrendingHashtag
+	if err := db.Preload("Hashtag").Order("rank").Limit(limit).Find(&trending).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trending hashtags", "details": err.Error()})
+		return
+	}
+
+	// Tell clients how fresh the list is
+	var computedAt *time.Time
+	if len(trending) > 0 {
+		computedAt = &trending[0].ComputedAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trending": trending, "computed_at": computedAt})
+}
+
+// Cron job or background task to recompute the trending hashtags
+func RecomputeTrendingHashtags() {
+	now := time.Now().UTC()
+
+	log.Println("Running RecomputeTrendingHashtags at:", now)
+
+	count, err := saveTrendingHashtags(config.GetDB(), now)
+	if err != nil {
+		log.Println("Error recomputing trending hashtags:", err)
+		return
+	}
+
+	log.Println("Trending hashtags computed:", count)
+}
+
+// refreshStaleTrending recomputes the trending hashtags when the stored list
+// is more than trendingMaxAge old or empty. Each instance checks at most once
+// per trendingRecheck, and the recompute re-checks the age under a lock so
+// concurrent instances do it once.
+func refreshStaleTrending(db *gorm.DB, now time.Time) error {
+	trendingChecked.Lock()
+	defer trendingChecked.Unlock()
+	if now.Sub(trendingChecked.at) < trendingRecheck {
+		return nil
+	}
+	trendingChecked.at = now
+
+	stale, err := trendingStale(db, now)
+	if err != nil || !stale {
+		return err
+	}
+	_, err = saveTrendingHashtags(db, now)
+	return err
+}
+
+// trendingStale reports whether the stored trending list is empty or older than trendingMaxAge
+func trendingStale(db *gorm.DB, now time.Time) (bool, error) {
+	var newest models.TrendingHashtag
+	if err := db.Select("computed_at").Order("computed_at DESC").First(&newest).Error; err == gorm.ErrRecordNotFound {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return now.Sub(newest.ComputedAt) >= trendingMaxAge, nil
+}
+
+// saveTrendingHashtags computes the trending hashtags at now, swaps them in
+// for the stored ones and drops usage too old to matter. It returns how many
+// hashtags trend. Writers are serialized on Postgres, and one that finds the
+// list already recomputed by another leaves it alone.
+func saveTrendingHashtags(db *gorm.DB, now time.Time) (int, error) {
+	count := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("LOCK TABLE trending_hashtags IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+			var newer int64
+			if err := tx.Model(&models.TrendingHashtag{}).Where("computed_at > ?", now.Add(-trendingRecheck)).Count(&newer).Error; err != nil || newer > 0 {
+				return err
+			}
+		}
+
+		trending, err := computeTrendingHashtags(tx, now)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.TrendingHashtag{}).Error; err != nil {
+			return err
+		}
+		if len(trending) > 0 {
+			if err := tx.Create(&trending).Error; err != nil {
+				return err
+			}
+		}
+		count = len(trending)
+		return tx.Where("hour < ?", trendingWindowStart(now).Add(-trendingBaseline)).Delete(&models.HashtagUsage{}).Error
+	})
+	return count, err
+}
+
+// computeTrendingHashtags ranks hashtags by how far their usage in the window
+// ending at now exceeds their baseline rate. It only reads usage buckets, so
+// passing a fixed now gives a deterministic result.
+func computeTrendingHashtags(db *gorm.DB, now time.Time) ([]models.TrendingHashtag, error) {
+	windowStart := trendingWindowStart(now)
+	baselineStart := windowStart.Add(-trendingBaseline)
+
+	// Sum each tag's uses inside and before the window
+	var usage []struct {
+		HashtagID    uint
+		WindowUses   int64
+		BaselineUses int64
+	}
+	if err := db.Model(&models.HashtagUsage{}).
+		Select("hashtag_id, SUM(CASE WHEN hour >= ? THEN uses ELSE 0 END) AS window_uses, SUM(CASE WHEN hour < ? THEN uses ELSE 0 END) AS baseline_uses", windowStart, windowStart).
+		Where("hour >= ? AND hour <= ?", baselineStart, now).
+		Group("hashtag_id").Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	trending := []models.TrendingHashtag{}
+	for _, u := range usage {
+		if u.WindowUses < trendingMinUses {
+			continue
+		}
+		score := trendingScore(u.WindowUses, u.BaselineUses)
+		if score <= 0 {
+			continue
+		}
+		trending = append(trending, models.TrendingHashtag{
+			HashtagID:    u.HashtagID,
+			Score:        score,
+			WindowUses:   u.WindowUses,
+			BaselineRate: float64(u.BaselineUses) / trendingBaseline.Hours(),
+			ComputedAt:   now,
+		})
+	}
+
+	sort.Slice(trending, func(i, j int) bool {
+		if trending[i].Score != trending[j].Score {
+			return trending[i].Score > trending[j].Score
+		}
+		if trending[i].WindowUses != trending[j].WindowUses {
+			return trending[i].WindowUses > trending[j].WindowUses
+		}
+		return trending[i].HashtagID < trending[j].HashtagID
+	})
+	if len(trending) > trendingKeep {
+		trending = trending[:trendingKeep]
+	}
+	for i := range trending {
+		trending[i].Rank = i + 1
+	}
+	return trending, nil
+}
+
+// trendingScore compares the uses in the window with the uses the baseline rate
+// predicts for a window of the same length. The difference is scaled by the
+// square root of the prediction so that a jump from 0 to 10 outranks one from
+// 1000 to 1010.
+func trendingScore(windowUses, baselineUses int64) float64 {
+	expected := float64(baselineUses) * trendingWindow.Hours() / trendingBaseline.Hours()
+	return (float64(windowUses) - expected) / math.Sqrt(expected+1)
+}
+
+// trendingWindowStart returns the first hour bucket inside the window ending at now
+func trendingWindowStart(now time.Time) time.Time {
+	return now.UTC().Truncate(time.Hour).Add(time.Hour - trendingWindow)
+}
+
+// recordHashtagUsage adds one use to each hashtag's bucket for the hour containing at
+func recordHashtagUsage(tx *gorm.DB, hashtagIDs []uint, at time.Time) error {
+	hour := at.UTC().Truncate(time.Hour)
+	for _, id := range hashtagIDs {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hashtag_id"}, {Name: "hour"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"uses": gorm.Expr("hashtag_usages.uses + 1")}),
+		}).Create(&models.HashtagUsage{HashtagID: id, Hour: hour, Uses: 1}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
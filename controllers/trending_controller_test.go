@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"math"
+	"pixi/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// trendingNow is the fixed time the trending tests compute at: the window
+// covers the 10:00, 11:00 and 12:00 buckets
+var trendingNow = time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)
+
+// addUsage records uses of a hashtag in the bucket starting at hour
+func addUsage(t *testing.T, db *gorm.DB, hashtagID uint, hour time.Time, uses int64) {
+	t.Helper()
+	if err := db.Create(&models.HashtagUsage{HashtagID: hashtagID, Hour: hour, Uses: uses}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrendingScore(t *testing.T) {
+	tests := []struct {
+		name                     string
+		windowUses, baselineUses int64
+		want                     float64
+	}{
+		{"new tag", 10, 0, 10},
+		{"no uses at all", 0, 0, 0},
+		{"at the baseline rate", 10, 560, 0}, // 560 uses over 168 hours predicts 10 in 3 hours
+		{"below the baseline rate", 5, 560, -5 / math.Sqrt(11)},
+		{"slightly above a large baseline", 1010, 56000, 10 / math.Sqrt(1001)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trendingScore(tt.windowUses, tt.baselineUses); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("trendingScore(%d, %d) = %v, want %v", tt.windowUses, tt.baselineUses, got, tt.want)
+			}
+		})
+	}
+
+	if trendingScore(10, 0) <= trendingScore(1010, 56000) {
+		t.Fatal("a jump from 0 to 10 should outrank one from 1000 to 1010")
+	}
+}
+
+func TestComputeTrendingHashtags(t *testing.T) {
+	db := newTestDB(t)
+	hour := func(h int) time.Time { return time.Date(2026, 10, 19, h, 0, 0, 0, time.UTC) }
+	windowStart := hour(10)
+	baselineStart := windowStart.Add(-trendingBaseline)
+
+	// 1: new, 10 uses in the window
+	addUsage(t, db, 1, hour(10), 4)
+	addUsage(t, db, 1, hour(12), 6)
+	// 2: 1010 uses in the window against a baseline predicting 1000
+	addUsage(t, db, 2, hour(11), 1010)
+	addUsage(t, db, 2, baselineStart, 56000)
+	// 3: too few uses in the window to trend
+	addUsage(t, db, 3, hour(11), trendingMinUses-1)
+	// 4: used less than its baseline
+	addUsage(t, db, 4, hour(11), 5)
+	addUsage(t, db, 4, hour(9), 560)
+	// 5 and 7: tied at 4 uses, with anything outside the baseline ignored
+	addUsage(t, db, 7, hour(12), 4)
+	addUsage(t, db, 5, hour(10), 4)
+	addUsage(t, db, 5, baselineStart.Add(-time.Hour), 1000)
+	// 6: only used after now
+	addUsage(t, db, 6, hour(13), 20)
+	// 8: only used the hour before the window, which is baseline
+	addUsage(t, db, 8, hour(9), 30)
+
+	trending, err := computeTrendingHashtags(db, trendingNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		hashtagID    uint
+		windowUses   int64
+		baselineRate float64
+	}{
+		{1, 10, 0},
+		{5, 4, 0},
+		{7, 4, 0},
+		{2, 1010, 56000 / trendingBaseline.Hours()},
+	}
+	if len(trending) != len(want) {
+		t.Fatalf("got %d trending hashtags, want %d: %+v", len(trending), len(want), trending)
+	}
+	for i, w := range want {
+		got := trending[i]
+		if got.HashtagID != w.hashtagID || got.Rank != i+1 || got.WindowUses != w.windowUses ||
+			math.Abs(got.BaselineRate-w.baselineRate) > 1e-9 || !got.ComputedAt.Equal(trendingNow) {
+			t.Errorf("trending[%d] = %+v, want hashtag %d at rank %d with %d uses and baseline rate %v",
+				i, got, w.hashtagID, i+1, w.windowUses, w.baselineRate)
+		}
+		if score := trendingScore(got.WindowUses, int64(math.Round(got.BaselineRate*trendingBaseline.Hours()))); got.Score != score {
+			t.Errorf("trending[%d].Score = %v, want %v", i, got.Score, score)
+		}
+	}
+}
+
+func TestComputeTrendingHashtagsKeepsTopN(t *testing.T) {
+	db := newTestDB(t)
+
+	// Hashtag i is used trendingMinUses+i times, so higher IDs rank first
+	total := trendingKeep + 5
+	for i := 1; i <= total; i++ {
+		addUsage(t, db, uint(i), trendingNow.Truncate(time.Hour), int64(trendingMinUses+i))
+	}
+
+	trending, err := computeTrendingHashtags(db, trendingNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trending) != trendingKeep {
+		t.Fatalf("got %d trending hashtags, want %d", len(trending), trendingKeep)
+	}
+	for i, got := range trending {
+		if wantID := uint(total - i); got.HashtagID != wantID || got.Rank != i+1 {
+			t.Fatalf("trending[%d] = hashtag %d at rank %d, want hashtag %d at rank %d", i, got.HashtagID, got.Rank, wantID, i+1)
+		}
+	}
+}
+
+func TestRefreshStaleTrending(t *testing.T) {
+	db := newTestDB(t)
+	trendingChecked.at = time.Time{}
+	t.Cleanup(func() { trendingChecked.at = time.Time{} })
+
+	hashtag := &models.Hashtag{Name: "sunset"}
+	mustCreate(t, db, hashtag)
+	addUsage(t, db, hashtag.ID, trendingNow.Truncate(time.Hour), 10)
+
+	// An empty list is computed on the first read
+	if err := refreshStaleTrending(db, trendingNow); err != nil {
+		t.Fatal(err)
+	}
+	var trending []models.TrendingHashtag
+	if err := db.Find(&trending).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(trending) != 1 || trending[0].HashtagID != hashtag.ID || !trending[0].ComputedAt.Equal(trendingNow) {
+		t.Fatalf("trending = %+v, want %q computed at %v", trending, hashtag.Name, trendingNow)
+	}
+
+	// A list younger than trendingMaxAge is kept, even once the recheck interval passed
+	later := trendingNow.Add(trendingMaxAge - time.Minute)
+	if err := refreshStaleTrending(db, later); err != nil {
+		t.Fatal(err)
+	}
+	var kept models.TrendingHashtag
+	if err := db.First(&kept).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !kept.ComputedAt.Equal(trendingNow) {
+		t.Fatalf("list recomputed at %v, want it kept from %v", kept.ComputedAt, trendingNow)
+	}
+
+	// An older one is recomputed, and the tag has left the window by then
+	muchLater := trendingNow.Add(trendingMaxAge + trendingWindow)
+	if err := refreshStaleTrending(db, muchLater); err != nil {
+		t.Fatal(err)
+	}
+	var remaining int64
+	if err := db.Model(&models.TrendingHashtag{}).Count(&remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Fatalf("%d trending hashtags after the window passed, want 0", remaining)
+	}
+}
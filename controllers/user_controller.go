@@ -72,18 +72,39 @@ func Login(c *gin.Context) {
 
 // CreateUser creates a new user
 func CreateUser(c *gin.Context) {
-	var newUser models.User
-
-	// Bind the request JSON to the newUser object
-	if err := c.ShouldBindJSON(&newUser); err != nil {
+	// Bind the request JSON; HideOffensive is a pointer so an explicit opt-out is not lost to the column default
+	var input struct {
+		models.User
+		HideOffensive *bool `json:"HideOffensive"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
 		log.Printf("Error binding JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	newUser := input.User
+
+	// Counters are maintained server-side and never taken from the client
+	newUser.FollowerCount, newUser.FollowingCount, newUser.PostCount = 0, 0, 0
+
+	// Validate the mention policy, leaving the default when it is omitted
+	if newUser.MentionPolicy != "" && !models.ValidMentionPolicy(newUser.MentionPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "MentionPolicy must be everyone, following or nobody"})
+		return
+	}
 
-	// Save the new user to the database
+	// Save the new user to the database; a false flag is left to the column default on insert, so an opt-out is written after
 	db := config.GetDB()
-	if err := newUser.Save(db); err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := newUser.Save(tx); err != nil {
+			return err
+		}
+		if input.HideOffensive != nil && !*input.HideOffensive {
+			newUser.HideOffensive = false
+			return tx.Model(&newUser).Update("hide_offensive", false).Error
+		}
+		return nil
+	}); err != nil {
 		log.Printf("Error saving user: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving user"})
 		return
@@ -138,15 +159,20 @@ func generateJWT(user models.User) (string, int64, error) {
 
 // UpdateUser updates an existing user's details
 func UpdateUser(c *gin.Context) {
-	db := config.GetDB() // Get the database connection
-	var user models.User
+	db := config.GetDB()    // Get the database connection
 	userID := c.Param("id") // Get the user ID from the URL params
 
-	// Bind the input JSON data
-	if err := c.BindJSON(&user); err != nil {
+	// Bind the input JSON data; flags are pointers so an explicit false can be told apart from an omitted field
+	var input struct {
+		models.User
+		IsPrivate     *bool `json:"IsPrivate"`
+		HideOffensive *bool `json:"HideOffensive"`
+	}
+	if err := c.BindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	user := input.User
 
 	// Find the user by ID
 	var existingUser models.User
@@ -155,6 +181,12 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// Users can only update their own account
+	if existingUser.ID != viewerID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own account"})
+		return
+	}
+
 	// Check if the password was updated and hash it
 	if user.Password != "" {
 		if err := user.HashPassword(); err != nil {
@@ -174,9 +206,45 @@ func UpdateUser(c *gin.Context) {
 	if user.Email != "" {
 		existingUser.Email = user.Email
 	}
+	if user.MentionPolicy != "" {
+		if !models.ValidMentionPolicy(user.MentionPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "MentionPolicy must be everyone, following or nobody"})
+			return
+		}
+		existingUser.MentionPolicy = user.MentionPolicy
+	}
+
+	if input.HideOffensive != nil {
+		existingUser.HideOffensive = *input.HideOffensive
+	}
 
-	// Save the updated user data
-	if err := db.Save(&existingUser).Error; err != nil {
+	// Switching a private account to public approves every pending follow request
+	approvePending := false
+	if input.IsPrivate != nil {
+		approvePending = existingUser.IsPrivate && !*input.IsPrivate
+		existingUser.IsPrivate = *input.IsPrivate
+	}
+
+	// Save the updated user data, leaving the maintained counters untouched
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("FollowerCount", "FollowingCount", "PostCount").Save(&existingUser).Error; err != nil {
+			return err
+		}
+		if !approvePending {
+			return nil
+		}
+
+		var pending []models.Follow
+		if err := tx.Where("following_id = ? AND status = ?", existingUser.ID, models.FollowStatusPending).Find(&pending).Error; err != nil {
+			return err
+		}
+		for i := range pending {
+			if err := acceptFollow(tx, &pending[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
@@ -192,8 +260,8 @@ func GetUsers(c *gin.Context) {
 	// Declare a variable to hold the list of users
 	var users []models.User
 
-	// Fetch all users from the database
-	if err := db.Find(&users).Error; err != nil {
+	// Fetch all users from the database, leaving out anyone on either side of a block with the viewer
+	if err := db.Scopes(notBlocked(viewerID(c), "users.id")).Find(&users).Error; err != nil {
 		// If an error occurs, return an error response
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
@@ -215,6 +283,27 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
+	// Blocked profiles look the same as missing ones
+	blocked, err := isBlocked(db, user.ID, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking block", "details": err.Error()})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Tell a logged in viewer how they relate to this profile
+	if viewer := viewerID(c); viewer != 0 && viewer != user.ID {
+		relationships, err := relationshipsFor(db, viewer, []uint{user.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve relationship", "details": err.Error()})
+			return
+		}
+		user.Relationship = relationships[user.ID]
+	}
+
 	c.JSON(http.StatusOK, user)
 }
 
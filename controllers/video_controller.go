@@ -0,0 +1,445 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"pixi/config"
+	"pixi/models"
+	"pixi/storage"
+	"pixi/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Video processing settings, unless MAX_VIDEO_SECONDS, VIDEO_JOB_TIMEOUT,
+// VIDEO_JOB_BATCH or VIDEO_JOB_MAX_ATTEMPTS say otherwise
+const (
+	defaultMaxVideoSeconds    = 90
+	defaultVideoJobTimeout    = 10 * time.Minute // Longest one attempt may run; attempts still running after this are counted as failed
+	defaultVideoJobBatch      = 2                // Jobs run per ProcessVideoJobs call
+	defaultVideoJobAttempts   = 3
+	videoJobRetryDelay        = time.Minute // Before the second attempt, doubling for each one after
+	videoPosterFrameAt        = time.Second // Or halfway through shorter videos
+	videoProcessingFailedText = "Video could not be processed; try uploading it again"
+)
+
+// Reasons a video is refused outright rather than retried
+var (
+	errVideoTooLarge = errors.New("video file is too large")
+	errVideoTooLong  = errors.New("video is too long")
+)
+
+// errMediaGone is returned when media is deleted while its video is processed
+var errMediaGone = errors.New("media was deleted during processing")
+
+// isVideoRejected reports whether err is a problem with the video itself,
+// which another attempt would not fix
+func isVideoRejected(err error) bool {
+	return errors.Is(err, utils.ErrInvalidVideo) || errors.Is(err, errVideoTooLarge) ||
+		errors.Is(err, errVideoTooLong) || isImageError(err)
+}
+
+// uploadVideo stores a video sent to UploadMedia as it is and queues it for
+// processing
+func uploadVideo(c *gin.Context, file io.Reader, size int64, contentType string) {
+	db := config.GetDB()
+	viewer := viewerID(c)
+
+	if size > maxVideoBytes() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Video is too large"})
+		return
+	}
+
+	// Store the raw file for the job to transcode
+	store := config.GetStorage()
+	key, err := storage.NewKey(mediaKeyPrefix(viewer), mediaTypes[contentType])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store video", "details": err.Error()})
+		return
+	}
+	if err := store.Put(c.Request.Context(), key, file, size, contentType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store video", "details": err.Error()})
+		return
+	}
+
+	// Record the media and queue its processing together
+	media := models.Media{
+		UserID:      viewer,
+		Kind:        models.MediaKindVideo,
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return queueVideo(tx, &media)
+	}); err != nil {
+		deleteStoredObjects([]string{key})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Video uploaded successfully and queued for processing", "media": media})
+}
+
+// completeVideoUpload checks a direct video upload arrived and really is the
+// type it was started with, then queues it for processing. Its size is
+// checked when the job downloads it.
+func completeVideoUpload(c *gin.Context, media *models.Media) {
+	db := config.GetDB()
+
+	// Read the start of the uploaded file back to check its type
+	object, err := config.GetStorage().Get(c.Request.Context(), media.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "File has not been uploaded yet"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(object, head)
+	object.Close()
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file", "details": err.Error()})
+		return
+	}
+
+	if utils.DetectVideoType(head[:n]) != media.ContentType {
+		if err := db.Delete(media).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media", "details": err.Error()})
+			return
+		}
+		deleteStoredObjects([]string{media.Key})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is not the video type the upload was started with"})
+		return
+	}
+
+	// The raw file is not served; the URL points at a rendition once processed
+	media.URL = ""
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return queueVideo(tx, media)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Video upload completed and queued for processing", "media": media})
+}
+
+// queueVideo saves video media as processing and creates the job that
+// processes it
+func queueVideo(tx *gorm.DB, media *models.Media) error {
+	media.Status = models.MediaStatusProcessing
+	if err := tx.Save(media).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.VideoJob{
+		MediaID:     media.ID,
+		Status:      models.VideoJobQueued,
+		RunAt:       time.Now(),
+		MaxAttempts: envInt("VIDEO_JOB_MAX_ATTEMPTS", defaultVideoJobAttempts),
+	}).Error
+}
+
+// ProcessVideoJobs runs up to VIDEO_JOB_BATCH queued video jobs that are due,
+// see processVideo. Each job is claimed before it runs, so overlapping calls
+// never process the same video twice. Failed attempts are retried with a
+// growing delay; videos that are invalid or fail every attempt are marked
+// failed, and so are the posts waiting for them. cmd/video-worker calls it in
+// a loop.
+func ProcessVideoJobs() {
+	db := config.GetDB()
+
+	log.Println("Running ProcessVideoJobs at:", time.Now().UTC())
+
+	timeout, err := time.ParseDuration(utils.GetEnv("VIDEO_JOB_TIMEOUT", defaultVideoJobTimeout.String()))
+	if err != nil || timeout <= 0 {
+		timeout = defaultVideoJobTimeout
+	}
+
+	// Attempts whose worker stopped without finishing, such as a function that timed out, count as failed
+	var stale []models.VideoJob
+	if err := db.Where("status = ? AND started_at < ?", models.VideoJobRunning, time.Now().Add(-timeout-time.Minute)).
+		Find(&stale).Error; err != nil {
+		log.Println("Error finding stalled video jobs:", err)
+		return
+	}
+	for i := range stale {
+		failVideoJob(db, &stale[i], errors.New("attempt did not finish in time"))
+	}
+
+	processed := 0
+	for processed < envInt("VIDEO_JOB_BATCH", defaultVideoJobBatch) {
+		var job models.VideoJob
+		err := db.Where("status = ? AND run_at <= ?", models.VideoJobQueued, time.Now()).Order("run_at").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			log.Println("Error fetching video jobs:", err)
+			return
+		}
+
+		// Claim the job; another worker may have taken it first
+		now := time.Now()
+		result := db.Model(&models.VideoJob{}).Where("id = ? AND status = ?", job.ID, models.VideoJobQueued).Updates(map[string]interface{}{
+			"status":     models.VideoJobRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"started_at": now,
+		})
+		if result.Error != nil {
+			log.Println("Error claiming video job (ID:", job.ID, "):", result.Error)
+			return
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		job.Status, job.Attempts, job.StartedAt = models.VideoJobRunning, job.Attempts+1, &now
+		processed++
+
+		runVideoJob(db, &job, timeout)
+	}
+
+	log.Println("Video jobs processed:", processed)
+}
+
+// runVideoJob makes one attempt at a claimed job
+func runVideoJob(db *gorm.DB, job *models.VideoJob, timeout time.Duration) {
+	log.Println("Processing video (job ID:", job.ID, "media ID:", job.MediaID, "attempt:", job.Attempts, ")")
+
+	var media models.Media
+	if err := db.Where("id = ? AND status = ?", job.MediaID, models.MediaStatusProcessing).First(&media).Error; err != nil {
+		log.Println("Dropping video job for missing media (job ID:", job.ID, "):", err)
+		if err := db.Delete(job).Error; err != nil {
+			log.Println("Error deleting video job (ID:", job.ID, "):", err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rawKey := media.Key
+	stored, err := processVideo(ctx, &media)
+	if err != nil {
+		failVideoJob(db, job, err)
+		return
+	}
+
+	// Mark the media ready, show it in its post and publish posts that were waiting for it
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&media).Where("status = ?", models.MediaStatusProcessing).
+			Select("key", "url", "content_type", "size", "width", "height", "duration_ms", "renditions", "status", "failure_reason").
+			Updates(&media)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errMediaGone
+		}
+		if err := tx.Model(job).Updates(map[string]interface{}{"status": models.VideoJobDone, "last_error": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PostMedia{}).Where("media_id = ?", media.ID).
+			Updates(map[string]interface{}{"url": media.URL, "width": media.Width, "height": media.Height}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Post{}).Where("media_id = ?", media.ID).Update("image_url", media.PreviewURL()).Error; err != nil {
+			return err
+		}
+		return publishWaitingPosts(tx, media.ID)
+	}); err != nil {
+		deleteStoredObjects(stored)
+		if errors.Is(err, errMediaGone) {
+			log.Println("Discarding processed video (job ID:", job.ID, "):", err)
+			return
+		}
+		failVideoJob(db, job, err)
+		return
+	}
+	deleteStoredObjects([]string{rawKey})
+
+	log.Println("Video processed successfully (job ID:", job.ID, "media ID:", media.ID, ")")
+}
+
+// processVideo downloads a raw video, checks it with ffprobe, stores image
+// renditions of a poster frame and transcodes the video renditions in
+// utils.VideoRenditionSizes, then points media at the largest of them. It
+// returns the keys of the stored files, to delete if the media cannot be
+// saved; on error it deletes them itself.
+func processVideo(ctx context.Context, media *models.Media) ([]string, error) {
+	dir, err := os.MkdirTemp("", "pixi-video-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source")
+	size, err := downloadVideo(ctx, media.Key, source)
+	if err != nil {
+		return nil, err
+	}
+	info, err := utils.ProbeVideo(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if maxSeconds := envInt("MAX_VIDEO_SECONDS", defaultMaxVideoSeconds); info.Duration > time.Duration(maxSeconds)*time.Second {
+		return nil, fmt.Errorf("%w: the limit is %d seconds", errVideoTooLong, maxSeconds)
+	}
+
+	// The poster frame gets the same renditions as an uploaded image
+	frame, err := utils.ExtractPosterFrame(ctx, source, min(videoPosterFrameAt, info.Duration/2))
+	if err != nil {
+		return nil, err
+	}
+	poster := models.Media{UserID: media.UserID}
+	if err := storeImageRenditions(ctx, &poster, frame); err != nil {
+		return nil, err
+	}
+	stored, renditions := poster.StorageKeys(), poster.Renditions
+
+	// Transcode each rendition the source is large enough for, and always the smallest
+	store := config.GetStorage()
+	base, err := storage.NewKey(mediaKeyPrefix(media.UserID), "")
+	if err != nil {
+		deleteStoredObjects(stored)
+		return nil, err
+	}
+	largest := ""
+	for i, name := range utils.VideoRenditionNames {
+		if i > 0 && min(info.Width, info.Height) <= utils.VideoRenditionSizes[utils.VideoRenditionNames[i-1]] {
+			break
+		}
+		width, height := utils.VideoRenditionSize(info.Width, info.Height, utils.VideoRenditionSizes[name])
+		output := filepath.Join(dir, name+".mp4")
+		if err := utils.TranscodeVideo(ctx, source, output, width, height); err != nil {
+			deleteStoredObjects(stored)
+			return nil, err
+		}
+		key := base + "_" + name + ".mp4"
+		if err := putFile(ctx, store, key, output, utils.VideoTypeMP4); err != nil {
+			deleteStoredObjects(stored)
+			return nil, err
+		}
+		stored = append(stored, key)
+		renditions[name] = models.MediaRendition{Key: key, URL: store.URL(key), Width: width, Height: height}
+		largest = name
+	}
+
+	media.Key, media.URL, media.ContentType = renditions[largest].Key, renditions[largest].URL, utils.VideoTypeMP4
+	media.Size, media.Width, media.Height, media.DurationMs = size, info.Width, info.Height, info.Duration.Milliseconds()
+	media.Renditions, media.Status, media.FailureReason = renditions, models.MediaStatusReady, ""
+	return stored, nil
+}
+
+// downloadVideo copies a stored raw video to path and returns its size,
+// refusing files over MAX_VIDEO_BYTES
+func downloadVideo(ctx context.Context, key, path string) (int64, error) {
+	object, err := config.GetStorage().Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, fmt.Errorf("%w: the uploaded file is missing", utils.ErrInvalidVideo)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer object.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	written, err := io.Copy(file, io.LimitReader(object, maxVideoBytes()+1))
+	if err != nil {
+		return 0, err
+	}
+	if written > maxVideoBytes() {
+		return 0, errVideoTooLarge
+	}
+	return written, file.Close()
+}
+
+// putFile stores the file at path under key
+func putFile(ctx context.Context, store storage.Storage, key, path, contentType string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, key, file, stat.Size(), contentType)
+}
+
+// failVideoJob records a failed attempt. The job is queued again after a
+// delay unless the video was rejected or this was its last attempt; then the
+// media and the posts waiting for it are marked failed.
+func failVideoJob(db *gorm.DB, job *models.VideoJob, cause error) {
+	log.Println("Error processing video (job ID:", job.ID, "attempt:", job.Attempts, "):", cause)
+
+	if !isVideoRejected(cause) && job.Attempts < job.MaxAttempts {
+		delay := videoJobRetryDelay << (max(job.Attempts, 1) - 1)
+		if err := db.Model(job).Updates(map[string]interface{}{
+			"status":     models.VideoJobQueued,
+			"run_at":     time.Now().Add(delay),
+			"last_error": cause.Error(),
+		}).Error; err != nil {
+			log.Println("Error requeueing video job (ID:", job.ID, "):", err)
+		}
+		return
+	}
+
+	// Only problems with the video itself are worth showing its uploader
+	reason := videoProcessingFailedText
+	if isVideoRejected(cause) {
+		reason = "Invalid video: " + cause.Error()
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(job).Updates(map[string]interface{}{"status": models.VideoJobFailed, "last_error": cause.Error()}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Media{}).Where("id = ? AND status = ?", job.MediaID, models.MediaStatusProcessing).
+			Updates(map[string]interface{}{"status": models.MediaStatusFailed, "failure_reason": reason}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Post{}).
+			Where("status = ? AND id IN (SELECT post_id FROM post_media WHERE media_id = ?)", "processing", job.MediaID).
+			Update("status", "failed").Error
+	}); err != nil {
+		log.Println("Error failing video job (ID:", job.ID, "):", err)
+	}
+}
+
+// publishWaitingPosts publishes the posts held back for processing that
+// show the media, once none of their other media is still processing
+func publishWaitingPosts(tx *gorm.DB, mediaID uint) error {
+	var posts []models.Post
+	if err := tx.Where("status = ? AND id IN (SELECT post_id FROM post_media WHERE media_id = ?)", "processing", mediaID).
+		Find(&posts).Error; err != nil {
+		return err
+	}
+	for i := range posts {
+		status, err := postItemsStatus(tx, posts[i].ID)
+		if err != nil {
+			return err
+		}
+		if status != models.MediaStatusReady {
+			continue
+		}
+		if err := publishPost(tx, &posts[i]); err != nil {
+			return err
+		}
+		log.Println("Post published after processing (ID:", posts[i].ID, ")")
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"net/http"
+	"pixi/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// viewerID returns the authenticated user's ID, or 0 for anonymous requests
+func viewerID(c *gin.Context) uint {
+	if userID, exists := c.Get("userID"); exists {
+		return userID.(uint)
+	}
+	return 0
+}
+
+// publicUserColumns limits a query on users to the profile fields shown
+// next to other people's content, keeping password hashes and emails out of
+// responses. Use it to preload authors, actors and listed accounts.
+func publicUserColumns(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "username", "full_name", "profile_image")
+}
+
+// isFollowing reports whether follower has an accepted follow on following
+func isFollowing(db *gorm.DB, followerID, followingID uint) (bool, error) {
+	if followerID == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err := db.Model(&models.Follow{}).
+		Where("follower_id = ? AND following_id = ? AND status = ?", followerID, followingID, models.FollowStatusAccepted).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// isBlocked reports whether either user has blocked the other
+func isBlocked(db *gorm.DB, userA, userB uint) (bool, error) {
+	if userA == 0 || userB == 0 || userA == userB {
+		return false, nil
+	}
+
+	var count int64
+	err := db.Model(&models.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userA, userB, userB, userA).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// notBlocked scopes a query to rows whose user column is not on either side of a block with the viewer
+func notBlocked(viewer uint, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer == 0 {
+			return db
+		}
+		return db.Where(
+			column+" NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?) AND "+column+" NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?)",
+			viewer, viewer,
+		)
+	}
+}
+
+// notMuted scopes a query to rows whose user column the viewer has not muted.
+// muteColumn names the kind of mute to respect, e.g. "mute_posts" or "mute_comments".
+func notMuted(viewer uint, column, muteColumn string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer == 0 {
+			return db
+		}
+		return db.Where(
+			column+" NOT IN (SELECT muted_id FROM mutes WHERE muter_id = ? AND "+muteColumn+" = ?)",
+			viewer, true,
+		)
+	}
+}
+
+// canViewUserContent reports whether the viewer may see the owner's posts,
+// comments and follower lists. Private accounts are only open to themselves
+// and their approved followers, and blocks hide both parties from each other.
+func canViewUserContent(db *gorm.DB, owner *models.User, viewer uint) (bool, error) {
+	if owner.ID == viewer {
+		return true, nil
+	}
+	if blocked, err := isBlocked(db, owner.ID, viewer); err != nil || blocked {
+		return false, err
+	}
+	if !owner.IsPrivate {
+		return true, nil
+	}
+	return isFollowing(db, viewer, owner.ID)
+}
+
+// isCloseFriend reports whether friend is on the user's close friends list
+func isCloseFriend(db *gorm.DB, userID, friendID uint) (bool, error) {
+	if friendID == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err := db.Model(&models.CloseFriend{}).Where("user_id = ? AND friend_id = ?", userID, friendID).Count(&count).Error
+	return count > 0, err
+}
+
+// canViewPost reports whether the viewer may see a post given its audience.
+// Close friends posts are limited to the author's close friends list, follower
+// posts (and every post from a private account) to approved followers.
+// Posts are never shown across a block, and scheduled, processing and failed
+// posts are only shown to their author.
+func canViewPost(db *gorm.DB, post *models.Post, viewer uint) (bool, error) {
+	if post.UserID == viewer {
+		return true, nil
+	}
+	if post.Status != "published" {
+		return false, nil
+	}
+	if blocked, err := isBlocked(db, post.UserID, viewer); err != nil || blocked {
+		return false, err
+	}
+
+	switch post.Audience {
+	case models.AudienceCloseFriends:
+		return isCloseFriend(db, post.UserID, viewer)
+	case models.AudienceFollowers:
+		return isFollowing(db, viewer, post.UserID)
+	}
+
+	author := post.User
+	if author == nil {
+		author = &models.User{}
+		if err := db.First(author, post.UserID).Error; err != nil {
+			return false, err
+		}
+	}
+
+	if !author.IsPrivate {
+		return true, nil
+	}
+	return isFollowing(db, viewer, post.UserID)
+}
+
+// findVisiblePost loads a post and checks the viewer may see it, writing a
+// 404 response when the post is missing or hidden from them
+func findVisiblePost(c *gin.Context, db *gorm.DB, postID interface{}, post *models.Post) bool {
+	if err := db.Preload("User").First(post, postID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return false
+	}
+
+	visible, err := canViewPost(db, post, viewerID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking post visibility", "details": err.Error()})
+		return false
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return false
+	}
+	return true
+}
+
+// visiblePosts scopes a posts query to the posts the viewer is allowed to see
+func visiblePosts(viewer uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(notBlocked(viewer, "posts.user_id")).Where(
+			"(posts.user_id = ?"+
+				" OR (posts.audience = ? AND posts.user_id IN (SELECT user_id FROM close_friends WHERE friend_id = ?))"+
+				" OR (posts.audience IN ? AND posts.user_id IN (SELECT following_id FROM follows WHERE follower_id = ? AND status = ?))"+
+				" OR (posts.audience = ? AND posts.user_id IN (SELECT id FROM users WHERE is_private = ?)))",
+			viewer,
+			models.AudienceCloseFriends, viewer,
+			[]string{models.AudienceFollowers, models.AudiencePublic}, viewer, models.FollowStatusAccepted,
+			models.AudiencePublic, false,
+		)
+	}
+}
+
+// applyLikeVisibility hides like and reaction counts and liker lists on posts
+// whose author enabled HideLikeCounts. The author still sees everything, and
+// other viewers keep only their own like so they can tell whether they liked the post.
+func applyLikeVisibility(post *models.Post, viewer uint) {
+	if !post.HideLikeCounts || post.UserID == viewer {
+		return
+	}
+
+	post.LikeCount = 0
+	if post.Reactions != nil {
+		post.Reactions = map[string]int64{}
+	}
+	if post.Likes == nil {
+		return
+	}
+
+	ownLikes := []models.Like{}
+	for _, like := range post.Likes {
+		if viewer != 0 && like.UserID == viewer {
+			ownLikes = append(ownLikes, like)
+		}
+	}
+	post.Likes = ownLikes
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"pixi/models"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// assertVisiblePosts checks that canViewPost and visiblePosts both show the
+// viewer exactly the wanted posts, by caption. visiblePosts callers filter
+// unpublished posts themselves, so only published posts are checked there.
+func assertVisiblePosts(t *testing.T, db *gorm.DB, posts []*models.Post, viewer uint, want []string) {
+	t.Helper()
+	slices.Sort(want)
+
+	var checked, published []string
+	for _, post := range posts {
+		visible, err := canViewPost(db, post, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if visible {
+			checked = append(checked, post.Caption)
+			if post.Status == "published" {
+				published = append(published, post.Caption)
+			}
+		}
+	}
+	slices.Sort(checked)
+	slices.Sort(published)
+	if !slices.Equal(checked, want) {
+		t.Errorf("viewer %d: canViewPost shows %v, want %v", viewer, checked, want)
+	}
+
+	var scoped []string
+	if err := db.Model(&models.Post{}).Scopes(visiblePosts(viewer)).
+		Where("status = ?", "published").Order("caption").Pluck("caption", &scoped).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(scoped, published) {
+		t.Errorf("viewer %d: visiblePosts shows %v, want %v", viewer, scoped, published)
+	}
+}
+
+func TestPostVisibility(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	private := &models.User{FullName: "Private", Username: "private", Email: "private@example.com", Password: "x", IsPrivate: true}
+	follower := &models.User{FullName: "Follower", Username: "follower", Email: "follower@example.com", Password: "x"}
+	pending := &models.User{FullName: "Pending", Username: "pending", Email: "pending@example.com", Password: "x"}
+	stranger := &models.User{FullName: "Stranger", Username: "stranger", Email: "stranger@example.com", Password: "x"}
+	blocked := &models.User{FullName: "Blocked", Username: "blocked", Email: "blocked@example.com", Password: "x"}
+	mustCreate(t, db, author, private, follower, pending, stranger, blocked)
+	mustCreate(t, db,
+		&models.Follow{FollowerID: follower.ID, FollowingID: author.ID, Status: models.FollowStatusAccepted},
+		&models.Follow{FollowerID: follower.ID, FollowingID: private.ID, Status: models.FollowStatusAccepted},
+		&models.Follow{FollowerID: pending.ID, FollowingID: author.ID, Status: models.FollowStatusPending},
+		&models.Follow{FollowerID: pending.ID, FollowingID: private.ID, Status: models.FollowStatusPending},
+		&models.Follow{FollowerID: blocked.ID, FollowingID: author.ID, Status: models.FollowStatusAccepted},
+		&models.Block{BlockerID: author.ID, BlockedID: blocked.ID},
+	)
+
+	posts := []*models.Post{
+		{Caption: "public", ImageURL: "post", UserID: author.ID, Status: "published", Audience: models.AudiencePublic},
+		{Caption: "followers", ImageURL: "post", UserID: author.ID, Status: "published", Audience: models.AudienceFollowers},
+		{Caption: "scheduled", ImageURL: "post", UserID: author.ID, Status: "scheduled", Audience: models.AudiencePublic},
+		{Caption: "failed", ImageURL: "post", UserID: author.ID, Status: "failed", Audience: models.AudiencePublic},
+		{Caption: "private account", ImageURL: "post", UserID: private.ID, Status: "published", Audience: models.AudiencePublic},
+	}
+	for _, post := range posts {
+		mustCreate(t, db, post)
+	}
+
+	tests := []struct {
+		name   string
+		viewer uint
+		want   []string
+	}{
+		{"author sees every own post", author.ID, []string{"public", "followers", "scheduled", "failed"}},
+		{"private account sees its own post", private.ID, []string{"public", "private account"}},
+		{"follower", follower.ID, []string{"public", "followers", "private account"}},
+		{"pending follower", pending.ID, []string{"public"}},
+		{"stranger", stranger.ID, []string{"public"}},
+		{"anonymous", 0, []string{"public"}},
+		{"blocked follower", blocked.ID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertVisiblePosts(t, db, posts, tt.viewer, tt.want)
+		})
+	}
+}
+
+func TestCloseFriendsPostVisibility(t *testing.T) {
+	db := newTestDB(t)
+
+	author := &models.User{FullName: "Author", Username: "author", Email: "author@example.com", Password: "x"}
+	friend := &models.User{FullName: "Friend", Username: "friend", Email: "friend@example.com", Password: "x"}
+	follower := &models.User{FullName: "Follower", Username: "follower", Email: "follower@example.com", Password: "x"}
+	blocked := &models.User{FullName: "Blocked", Username: "blocked", Email: "blocked@example.com", Password: "x"}
+	mustCreate(t, db, author, friend, follower, blocked)
+	mustCreate(t, db,
+		&models.CloseFriend{UserID: author.ID, FriendID: friend.ID},
+		&models.CloseFriend{UserID: author.ID, FriendID: blocked.ID},
+		&models.CloseFriend{UserID: friend.ID, FriendID: follower.ID},
+		&models.Follow{FollowerID: follower.ID, FollowingID: author.ID, Status: models.FollowStatusAccepted},
+		&models.Block{BlockerID: blocked.ID, BlockedID: author.ID},
+	)
+
+	// The friend's own close friends post checks that the list belongs to the author
+	posts := []*models.Post{
+		{Caption: "public", ImageURL: "post", UserID: author.ID, Status: "published", Audience: models.AudiencePublic},
+		{Caption: "close friends", ImageURL: "post", UserID: author.ID, Status: "published", Audience: models.AudienceCloseFriends},
+		{Caption: "scheduled close friends", ImageURL: "post", UserID: author.ID, Status: "scheduled", Audience: models.AudienceCloseFriends},
+		{Caption: "friend's close friends", ImageURL: "post", UserID: friend.ID, Status: "published", Audience: models.AudienceCloseFriends},
+	}
+	for _, post := range posts {
+		mustCreate(t, db, post)
+	}
+
+	tests := []struct {
+		name   string
+		viewer uint
+		want   []string
+	}{
+		{"author", author.ID, []string{"public", "close friends", "scheduled close friends"}},
+		{"close friend", friend.ID, []string{"public", "close friends", "friend's close friends"}},
+		{"follower off the list", follower.ID, []string{"public", "friend's close friends"}},
+		{"close friend who blocked the author", blocked.ID, nil},
+		{"anonymous", 0, []string{"public"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertVisiblePosts(t, db, posts, tt.viewer, tt.want)
+		})
+	}
+}
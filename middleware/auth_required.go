@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"pixi/utils"
@@ -13,46 +14,61 @@ import (
 func AuthRequired() gin.HandlerFunc {
 
 	return func(c *gin.Context) {
-		// Get the token from the Authorization header
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" || len(tokenString) < 7 || tokenString[:7] != "Bearer " {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required or invalid format"})
-			c.Abort()
-			return
-		}
-		tokenString = tokenString[7:] // Remove "Bearer " prefix
-
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(utils.GetEnv("JWT_SECRET_KEY", "skjdrh8w7465734865rvb9giu8o74rt56y3847oqwv6yb578924b87o")), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		userID, err := userIDFromToken(c.GetHeader("Authorization"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		// Extract the user ID from the token's claims and set it in the context
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
+		c.Set("userID", userID) // Store user ID in the context
+		c.Next()
+	}
+}
+
+// AuthOptional middleware identifies the viewer when a valid JWT is present,
+// but lets anonymous requests through so public routes stay public
+func AuthOptional() gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		if userID, err := userIDFromToken(c.GetHeader("Authorization")); err == nil {
+			c.Set("userID", userID) // Store user ID in the context
 		}
+		c.Next()
+	}
+}
 
-		sub, ok := claims["sub"].(float64)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token claims"})
-			c.Abort()
-			return
+// userIDFromToken validates a "Bearer" Authorization header and returns the user ID it carries
+func userIDFromToken(tokenString string) (uint, error) {
+	// Get the token from the Authorization header
+	if tokenString == "" || len(tokenString) < 7 || tokenString[:7] != "Bearer " {
+		return 0, errors.New("Authorization token required or invalid format")
+	}
+	tokenString = tokenString[7:] // Remove "Bearer " prefix
+
+	// Parse and validate the token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		// Validate the signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		return []byte(utils.GetEnv("JWT_SECRET_KEY", "skjdrh8w7465734865rvb9giu8o74rt56y3847oqwv6yb578924b87o")), nil
+	})
 
-		c.Set("userID", uint(sub)) // Store user ID in the context
-		c.Next()
+	if err != nil || !token.Valid {
+		return 0, errors.New("Invalid or expired token")
+	}
+
+	// Extract the user ID from the token's claims
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, errors.New("Invalid token claims")
 	}
+
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, errors.New("Invalid user ID in token claims")
+	}
+
+	return uint(sub), nil
 }
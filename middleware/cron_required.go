@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"pixi/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CronRequired middleware only lets through requests carrying the
+// "Bearer $CRON_SECRET" Authorization header Vercel sends with cron jobs.
+// Every request is refused while CRON_SECRET is unset.
+func CronRequired() gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		secret := utils.GetEnv("CRON_SECRET", "")
+		expected := "Bearer " + secret
+		header := c.GetHeader("Authorization")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(header), []byte(expected)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing cron secret"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
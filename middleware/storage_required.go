@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"pixi/config"
+	"pixi/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageRequired middleware answers 503 while the media storage backend is
+// not configured, instead of letting uploads fail part way
+func StorageRequired() gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		if _, unavailable := config.GetStorage().(*storage.Unavailable); unavailable {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Media storage is not configured"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
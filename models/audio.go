@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// AudioClip is an uploaded voice recording. It is attached to at most one
+// comment through Comment.AudioID; clips never attached are cleaned up.
+type AudioClip struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;index"` // Uploader; only they can attach the clip
+	Key         string    `gorm:"not null"`       // Storage key of the file
+	URL         string    `gorm:"not null"`       // Where clients download the file
+	Format      string    `gorm:"not null"`       // wav, mp3, ogg or m4a
+	ContentType string    `gorm:"not null"`
+	Size        int64     `gorm:"not null"`                  // In bytes
+	DurationMs  int64     `gorm:"not null"`                  // Length of the recording
+	Peaks       []int     `gorm:"type:text;serializer:json"` // Waveform loudness per time slice, 0-100
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
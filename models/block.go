@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type Block struct {
+	ID        uint      `gorm:"primaryKey"`                                     // Primary key for the block record
+	BlockerID uint      `gorm:"not null;uniqueIndex:idx_blocker_blocked"`       // User who placed the block
+	BlockedID uint      `gorm:"not null;uniqueIndex:idx_blocker_blocked;index"` // User who is blocked
+	Blocker   *User     `gorm:"foreignKey:BlockerID"`
+	Blocked   *User     `gorm:"foreignKey:BlockedID"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
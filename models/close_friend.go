@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// CloseFriend is an entry on a user's close friends list, the audience for close_friends posts
+type CloseFriend struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_friend"`       // Owner of the list
+	FriendID  uint      `gorm:"not null;uniqueIndex:idx_user_friend;index"` // User on the list
+	Friend    *User     `gorm:"foreignKey:FriendID"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
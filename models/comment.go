@@ -1,18 +1,49 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
+// Comment is a comment on a post or, when ParentID is set, a reply to another
+// comment. Threads nest up to a configurable depth.
 type Comment struct {
-	ID           uint      `gorm:"primaryKey"`
-	Author       string    `gorm:"not null"`
-	Content      string    `gorm:"not null"`
-	AudioContent string    `gorm:"default:null"`
-	Replies      []Reply   `gorm:"foreignKey:CommentID"` // Correct the foreign key
-	CreatedAt    time.Time `gorm:"autoCreateTime"`       // Automatically set timestamp
-	UpdatedAt    time.Time `gorm:"autoUpdateTime"`       // Automatically update timestamp
-	PostID       uint      `gorm:"not null"`
-	UserID       uint      `gorm:"not null"`
-	User         *User     `gorm:"foreignKey:UserID"`
+	ID           uint             `gorm:"primaryKey"`
+	Author       string           `gorm:"not null"`
+	Content      string           `gorm:"not null"`
+	AudioContent string           `gorm:"default:null"`              // Deprecated: the URL of Audio, kept for older clients
+	AudioID      *uint            `gorm:"uniqueIndex"`               // Uploaded voice recording, see the audio controller
+	Audio        *AudioClip       `gorm:"foreignKey:AudioID"`        // The voice recording, when loaded
+	ParentID     *uint            `gorm:"index"`                     // Comment being replied to; nil for top-level comments
+	Depth        int              `gorm:"not null;default:0"`        // 0 for top-level comments, parent depth + 1 for replies
+	Path         string           `gorm:"not null;default:'';index"` // Ancestor IDs and this comment's own, see CommentPath
+	Replies      []Comment        `gorm:"foreignKey:ParentID"`       // Direct replies, when loaded
+	Mentions     []Mention        `gorm:"foreignKey:CommentID"`      // Users @mentioned in the content
+	LikeCount    int64            `gorm:"not null;default:0"`        // Number of reactions of any kind, maintained by the reaction controller
+	ReplyCount   int64            `gorm:"not null;default:0"`        // Number of direct replies not hidden, maintained by the comment controllers
+	PinnedAt     *time.Time       `gorm:"index"`                     // When the post's author pinned the comment; nil when not pinned
+	Hidden       bool             `gorm:"not null;default:false"`    // Hidden from everyone but the commenter and the post's author
+	HiddenReason string           `gorm:"not null;default:''"`       // Why the comment is hidden: author or filter
+	LikedByMe    bool             `gorm:"-"`                         // Whether the viewer reacted to the comment, filled in per request
+	MyReaction   string           `gorm:"-"`                         // The viewer's reaction, filled in per request
+	Reactions    map[string]int64 `gorm:"-"`                         // Reaction counts by emoji, filled in per request
+	CreatedAt    time.Time        `gorm:"autoCreateTime"`            // Automatically set timestamp
+	UpdatedAt    time.Time        `gorm:"autoUpdateTime"`            // Automatically update timestamp
+	PostID       uint             `gorm:"not null;index"`
+	UserID       uint             `gorm:"not null"`
+	User         *User            `gorm:"foreignKey:UserID"`
+}
+
+// Reasons a comment is hidden
+const (
+	HiddenByAuthor = "author" // The post's author hid it
+	HiddenByFilter = "filter" // It matched one of the post author's comment filters
+)
+
+// CommentPath returns the materialized path of comment id under a parent with
+// parentPath ("" for top-level comments). Each ancestor is a zero padded,
+// slash terminated ID, so a thread's comments share their root's path as a
+// prefix and sorting by path lists a thread depth first.
+func CommentPath(parentPath string, id uint) string {
+	return parentPath + fmt.Sprintf("%010d/", id)
 }
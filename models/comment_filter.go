@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// CommentFilter is a word or phrase a user does not want to see in comments
+// on their posts. Matching comments are hidden automatically.
+type CommentFilter struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_phrase"`
+	Phrase    string    `gorm:"not null;uniqueIndex:idx_user_phrase"` // Normalized with utils.NormalizeFilterPhrase
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// CommentLike is a user's reaction to a comment or reply, DefaultReaction for
+// plain likes. The unique index allows one reaction per user and comment.
+type CommentLike struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_comment"`
+	CommentID uint      `gorm:"not null;uniqueIndex:idx_user_comment;index"`
+	Emoji     string    `gorm:"not null;default:'❤️'"` // Reaction picked from the configured set
+	User      *User     `gorm:"foreignKey:UserID"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
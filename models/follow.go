@@ -5,11 +5,18 @@ import (
 )
 
 type Follow struct {
-	ID          uint      `gorm:"primaryKey"`                           // Primary key for the follow record
-	FollowerID  uint      `gorm:"index;not null"`                       // Foreign key for the follower
-	FollowingID uint      `gorm:"index;not null"`                       // Foreign key for the followee
-	Follower    User      `gorm:"foreignKey:FollowerID;references:ID"`  // Relationship to User via FollowerID
-	Following   User      `gorm:"foreignKey:FollowingID;references:ID"` // Relationship to User via FolloweeID
+	ID          uint      `gorm:"primaryKey"`                                        // Primary key for the follow record
+	FollowerID  uint      `gorm:"index;not null;uniqueIndex:idx_follower_following"` // Foreign key for the follower
+	FollowingID uint      `gorm:"index;not null;uniqueIndex:idx_follower_following"` // Foreign key for the followee
+	Follower    User      `gorm:"foreignKey:FollowerID;references:ID"`               // Relationship to User via FollowerID
+	Following   User      `gorm:"foreignKey:FollowingID;references:ID"`              // Relationship to User via FolloweeID
+	Status      string    `gorm:"index;not null;default:'accepted'"`                 // pending until a private account approves the request
 	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
+
+// Follow statuses; only accepted follows grant access to a private account's content
+const (
+	FollowStatusPending  = "pending"
+	FollowStatusAccepted = "accepted"
+)
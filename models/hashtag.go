@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Hashtag is a normalized (lowercase, without "#") tag parsed from post captions and descriptions
+type Hashtag struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"uniqueIndex;not null"` // Tag name without the leading "#"
+	PostCount int64     `gorm:"not null;default:0"`   // Number of posts using the tag
+	Posts     []Post    `gorm:"many2many:post_hashtags" json:",omitempty"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// HashtagFollow subscribes a user to a hashtag so its posts appear in their home feed
+type HashtagFollow struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_hashtag"`
+	HashtagID uint      `gorm:"not null;uniqueIndex:idx_user_hashtag;index"`
+	Hashtag   *Hashtag  `gorm:"foreignKey:HashtagID"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
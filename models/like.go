@@ -4,12 +4,18 @@ import (
 	"time"
 )
 
+// Like is a user's reaction to a post. Plain likes, and every like recorded
+// before reactions existed, are the DefaultReaction.
 type Like struct {
 	ID        uint      `gorm:"primaryKey"`
-	UserID    uint      `gorm:"not null"`
-	PostID    uint      `gorm:"not null"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_post"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_user_post;index"`
+	Emoji     string    `gorm:"not null;default:'❤️'"` // Reaction picked from the configured set
 	User      *User     `gorm:"foreignKey:UserID"`
 	Post      *Post     `gorm:"foreignKey:PostID"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 }
+
+// DefaultReaction is the heart given by the like endpoints
+const DefaultReaction = "❤️"
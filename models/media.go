@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// Media is an uploaded image or video shown in a post. Posts reference it
+// through PostMedia.MediaID; media no post uses is cleaned up.
+type Media struct {
+	ID            uint                      `gorm:"primaryKey"`
+	UserID        uint                      `gorm:"not null;index"`           // Uploader; only they can use the media in a post
+	Kind          string                    `gorm:"not null;default:'image'"` // image or video
+	Key           string                    `gorm:"not null"`                 // Storage key of the largest rendition, or of the raw file until processed
+	URL           string                    `gorm:"not null"`                 // Where clients download the largest rendition; empty until processed
+	ContentType   string                    `gorm:"not null"`                 // Of the file at URL
+	Size          int64                     `gorm:"not null;default:0"`       // Of the original upload, in bytes
+	Status        string                    `gorm:"not null;default:'ready'"` // See the media statuses
+	FailureReason string                    `gorm:"default:''"`               // Why processing failed, when it did
+	Width         int                       `gorm:"not null;default:0"`       // Of the upright original, in pixels
+	Height        int                       `gorm:"not null;default:0"`
+	DurationMs    int64                     `gorm:"not null;default:0"`        // Length of a video
+	Renditions    map[string]MediaRendition `gorm:"type:text;serializer:json"` // Resized copies by name: thumb, feed and full images (of the poster frame, for videos) and 480p and 720p videos
+	CreatedAt     time.Time                 `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time
+}
+
+// MediaRendition is one resized copy of an uploaded image or video
+type MediaRendition struct {
+	Key    string
+	URL    string
+	Width  int
+	Height int
+}
+
+// Media kinds
+const (
+	MediaKindImage = "image"
+	MediaKindVideo = "video"
+)
+
+// Media statuses. Direct uploads are pending until completed; videos are
+// then processing until a VideoJob transcodes them, and failed if it cannot.
+const (
+	MediaStatusPending    = "pending"
+	MediaStatusProcessing = "processing"
+	MediaStatusReady      = "ready"
+	MediaStatusFailed     = "failed"
+)
+
+// PreviewURL is the image to show for the media: the full rendition of an
+// image, or of a video's poster frame
+func (media *Media) PreviewURL() string {
+	if media.Kind == MediaKindVideo {
+		return media.Renditions["full"].URL
+	}
+	return media.URL
+}
+
+// StorageKeys lists every stored file of the media, for deleting them all
+func (media *Media) StorageKeys() []string {
+	keys := []string{media.Key}
+	for _, rendition := range media.Renditions {
+		if rendition.Key != media.Key {
+			keys = append(keys, rendition.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Mention is an @username in a post caption, comment or reply that resolved to
+// a user. Exactly one of PostID and CommentID is set. Start and End
+// are character offsets of the "@username" in the text, End exclusive, so
+// clients can render it as a link.
+type Mention struct {
+	ID          uint      `gorm:"primaryKey"`
+	MentionedID uint      `gorm:"not null;index"` // User who was mentioned
+	MentionerID uint      `gorm:"not null"`       // Author of the text containing the mention
+	PostID      *uint     `gorm:"index"`          // Set for mentions in a post caption
+	CommentID   *uint     `gorm:"index"`          // Set for mentions in a comment or reply
+	Username    string    `gorm:"not null"`       // Username as written, without the "@"
+	Start       int       `gorm:"not null"`
+	End         int       `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
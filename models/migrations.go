@@ -0,0 +1,247 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// All lists every model, in the order AutoMigrate creates their tables
+func All() []interface{} {
+	return []interface{}{
+		&DataMigration{},
+		&User{},
+		&Post{},
+		&Save{},
+		&Comment{},
+		&Like{},
+		&CommentLike{},
+		&CommentFilter{},
+		&AudioClip{},
+		&Media{},
+		&PostMedia{},
+		&PostMediaTag{},
+		&VideoJob{},
+		&Follow{},
+		&Block{},
+		&Mute{},
+		&CloseFriend{},
+		&SuggestionDismissal{},
+		&Hashtag{},
+		&HashtagFollow{},
+		&Mention{},
+		&Notification{},
+		&HashtagUsage{},
+		&TrendingHashtag{},
+	}
+}
+
+// Migrate brings the schema and data up to date: it cleans up rows that would
+// block new constraints, runs AutoMigrate, rewrites legacy data and creates
+// the search indexes. Some steps scan whole tables, so it runs once per
+// deploy from cmd/migrate rather than on every start-up.
+func Migrate(db *gorm.DB) error {
+	if err := PrepareSchema(db); err != nil {
+		return err
+	}
+	if err := db.AutoMigrate(All()...); err != nil {
+		return err
+	}
+	if err := MigrateLegacyData(db); err != nil {
+		return err
+	}
+	return PrepareSearch(db)
+}
+
+// DataMigration records a one-time data migration that has been applied, for
+// rewrites that must not be repeated once users can change the data again
+type DataMigration struct {
+	Name      string    `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// runOnce applies a one-time data migration unless it was applied before,
+// recording it in the same transaction
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var applied int64
+		if err := tx.Model(&DataMigration{}).Where("name = ?", name).Count(&applied).Error; err != nil {
+			return err
+		}
+		if applied > 0 {
+			return nil
+		}
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&DataMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+}
+
+// PrepareSchema cleans up existing rows that would stop AutoMigrate from
+// applying new constraints. It runs before AutoMigrate and is idempotent.
+func PrepareSchema(db *gorm.DB) error {
+	// Duplicate follows must go before the unique (follower_id, following_id) index is created.
+	// Counters are repaired by the reconciliation job.
+	if db.Migrator().HasTable(&Follow{}) {
+		if err := db.Exec("DELETE FROM follows WHERE id NOT IN (SELECT MIN(id) FROM follows GROUP BY follower_id, following_id)").Error; err != nil {
+			return err
+		}
+	}
+
+	// Likes get the same treatment before the unique (user_id, post_id) index
+	if db.Migrator().HasTable(&Like{}) {
+		if err := db.Exec("DELETE FROM likes WHERE id NOT IN (SELECT MIN(id) FROM likes GROUP BY user_id, post_id)").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateLegacyData rewrites rows created before a schema change into their
+// new shape. Every step is idempotent or runs once, so it is safe to run
+// repeatedly.
+func MigrateLegacyData(db *gorm.DB) error {
+	// Posts marked private before audiences existed become follower-only
+	if err := db.Model(&Post{}).
+		Where("is_private = ? AND audience = ?", true, AudiencePublic).
+		Update("audience", AudienceFollowers).Error; err != nil {
+		return err
+	}
+
+	// Posts that allowed comments before comment policies existed stay open to everyone
+	if err := db.Model(&Post{}).
+		Where("allow_comments = ? AND comment_policy = ?", true, CommentPolicyOff).
+		Update("comment_policy", CommentPolicyEveryone).Error; err != nil {
+		return err
+	}
+
+	// Comments used to be off unless a client asked for them, which none did,
+	// so posts made until comments were opened by default get them back
+	if err := runOnce(db, "open_comments_on_existing_posts", func(tx *gorm.DB) error {
+		return tx.Model(&Post{}).Where("comment_policy = ?", CommentPolicyOff).
+			Updates(map[string]interface{}{"comment_policy": CommentPolicyEveryone, "allow_comments": true}).Error
+	}); err != nil {
+		return err
+	}
+
+	// The offensive word list used to be off unless a user switched it on, so
+	// accounts made until it was on by default get it like new ones do
+	if err := runOnce(db, "hide_offensive_by_default", func(tx *gorm.DB) error {
+		return tx.Model(&User{}).Where("hide_offensive = ? OR hide_offensive IS NULL", false).
+			Update("hide_offensive", true).Error
+	}); err != nil {
+		return err
+	}
+
+	// Comments written before threading get their materialized path
+	var unpathed []uint
+	if err := db.Model(&Comment{}).Where("path = ''").Order("id").Pluck("id", &unpathed).Error; err != nil {
+		return err
+	}
+	for _, id := range unpathed {
+		if err := db.Model(&Comment{}).Where("id = ?", id).Update("path", CommentPath("", id)).Error; err != nil {
+			return err
+		}
+	}
+
+	// Single-image posts become carousels of one
+	if err := db.Exec(`INSERT INTO post_media (post_id, position, media_id, url, alt_text, width, height)
+		SELECT posts.id, 0, posts.media_id, posts.image_url, '', COALESCE(media.width, 0), COALESCE(media.height, 0)
+		FROM posts LEFT JOIN media ON media.id = posts.media_id
+		WHERE posts.image_url <> '' AND NOT EXISTS (SELECT 1 FROM post_media WHERE post_media.post_id = posts.id)`).Error; err != nil {
+		return err
+	}
+
+	// Replies from the old one-level model become comments one level down
+	if db.Migrator().HasTable("replies") {
+		if err := db.Transaction(foldReplies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyReply is a row of the replies table that predates threaded comments
+type legacyReply struct {
+	ID           uint
+	PostID       uint
+	UserID       uint
+	CommentID    *uint
+	Content      string
+	AudioContent *string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// foldReplies turns every legacy reply into a comment, moves its mentions and
+// notifications over, recomputes the counters and drops the replies table.
+// Replies whose comment no longer exists become top-level comments.
+func foldReplies(tx *gorm.DB) error {
+	var replies []legacyReply
+	if err := tx.Table("replies").Order("id").Find(&replies).Error; err != nil {
+		return err
+	}
+
+	hasMentionReplies := tx.Migrator().HasColumn(&Mention{}, "reply_id")
+	hasNotificationReplies := tx.Migrator().HasColumn(&Notification{}, "reply_id")
+
+	for _, reply := range replies {
+		var author User
+		if err := tx.Select("username").First(&author, reply.UserID).Error; err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		comment := Comment{
+			Author:    author.Username,
+			Content:   reply.Content,
+			PostID:    reply.PostID,
+			UserID:    reply.UserID,
+			CreatedAt: reply.CreatedAt,
+			UpdatedAt: reply.UpdatedAt,
+		}
+		if reply.AudioContent != nil {
+			comment.AudioContent = *reply.AudioContent
+		}
+
+		// Attach the reply under its comment when that comment still exists
+		var parent Comment
+		if reply.CommentID != nil {
+			if err := tx.Where("id = ? AND post_id = ?", *reply.CommentID, reply.PostID).First(&parent).Error; err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+		}
+		if parent.ID != 0 {
+			comment.ParentID = &parent.ID
+			comment.Depth = parent.Depth + 1
+		}
+
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&comment).Update("path", CommentPath(parent.Path, comment.ID)).Error; err != nil {
+			return err
+		}
+
+		if hasMentionReplies {
+			if err := tx.Exec("UPDATE mentions SET comment_id = ?, reply_id = NULL WHERE reply_id = ?", comment.ID, reply.ID).Error; err != nil {
+				return err
+			}
+		}
+		if hasNotificationReplies {
+			if err := tx.Exec("UPDATE notifications SET comment_id = ?, reply_id = NULL WHERE reply_id = ?", comment.ID, reply.ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	// Replies now count towards the post's comments and their parent's replies; hidden comments count towards neither
+	if err := tx.Exec("UPDATE comments SET reply_count = (SELECT COUNT(*) FROM comments AS children WHERE children.parent_id = comments.id AND children.hidden = false)").Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("UPDATE posts SET comment_count = (SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.hidden = false)").Error; err != nil {
+		return err
+	}
+
+	return tx.Migrator().DropTable("replies")
+}
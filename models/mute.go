@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// Mute hides a user's content from the muting user without unfollowing.
+// The muted user is never told and sees nothing different.
+type Mute struct {
+	ID           uint      `gorm:"primaryKey"`                           // Primary key for the mute record
+	MuterID      uint      `gorm:"not null;uniqueIndex:idx_muter_muted"` // User who muted
+	MutedID      uint      `gorm:"not null;uniqueIndex:idx_muter_muted"` // User who is muted
+	Muted        *User     `gorm:"foreignKey:MutedID"`
+	MutePosts    bool      `gorm:"default:false"` // Hide the muted user's posts from feeds
+	MuteStories  bool      `gorm:"default:false"` // Hide the muted user's stories
+	MuteComments bool      `gorm:"default:false"` // Hide the muted user's comments and replies
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification types
+const (
+	NotificationMention = "mention"
+)
+
+// Notification tells a user that someone interacted with them. PostID is set
+// whenever the notification relates to a post; CommentID narrows it down to a
+// comment or reply on that post.
+type Notification struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"` // User being notified
+	ActorID   uint      `gorm:"not null"`       // User who caused the notification
+	Actor     *User     `gorm:"foreignKey:ActorID"`
+	Type      string    `gorm:"not null"`
+	PostID    *uint     `gorm:"index"`
+	CommentID *uint     `gorm:"index"`
+	Read      bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
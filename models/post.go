@@ -5,20 +5,87 @@ import (
 )
 
 type Post struct {
-	ID             uint      `gorm:"primaryKey"` // Unique identifier for the post
-	Caption        string    `gorm:"not null"`   // Caption for the post
-	Description    string    `gorm:"default:''"` // Optional description for the post
-	ImageURL       string    `gorm:"not null"`   // URL of the post's image
-	ScheduledAt    time.Time `json:"ScheduledAt"`
-	CreatedAt      time.Time // Timestamp when the post was created
-	UpdatedAt      time.Time // Timestamp when the post was last updated
-	UserID         uint      `gorm:"not null;index"`      // ID of the user who created the post
-	User           *User     `gorm:"foreignKey:UserID"`   // User who created the post
-	AllowComments  bool      `gorm:"default:false"`       // Whether comments are allowed on the post
-	HideLikeCounts bool      `gorm:"default:false"`       // Whether to hide like counts on the post
-	IsPrivate      bool      `gorm:"default:false"`       // Whether the post is private
-	IsScheduled    bool      `gorm:"default:false"`       // Whether the post is scheduled
-	Status         string    `gorm:"default:'scheduled'"` // Add a Status field to track the post's status (draft, published, etc.)
-	Comments       []Comment `gorm:"foreignKey:PostID"`   // List of comments on the post
-	Likes          []Like    `gorm:"foreignKey:PostID"`   // List of likes on the post
+	ID             uint             `gorm:"primaryKey"` // Unique identifier for the post
+	Caption        string           `gorm:"not null"`   // Caption for the post
+	Description    string           `gorm:"default:''"` // Optional description for the post
+	ImageURL       string           `gorm:"not null"`   // Deprecated: the URL of the first item, kept for older clients
+	ScheduledAt    time.Time        `json:"ScheduledAt"`
+	CreatedAt      time.Time        // Timestamp when the post was created
+	UpdatedAt      time.Time        // Timestamp when the post was last updated
+	UserID         uint             `gorm:"not null;index"`              // ID of the user who created the post
+	User           *User            `gorm:"foreignKey:UserID"`           // User who created the post
+	AllowComments  bool             `gorm:"default:false"`               // Deprecated: kept in sync with CommentPolicy for older clients
+	CommentPolicy  string           `gorm:"not null;default:'everyone'"` // Who can comment: everyone, followers, following or off
+	HideLikeCounts bool             `gorm:"default:false"`               // Whether to hide like counts on the post
+	IsPrivate      bool             `gorm:"default:false"`               // Deprecated: kept in sync with Audience for older clients
+	Audience       string           `gorm:"not null;default:'public'"`   // Who can see the post: public, followers or close_friends
+	IsScheduled    bool             `gorm:"default:false"`               // Whether the post is scheduled
+	Status         string           `gorm:"default:'scheduled'"`         // scheduled, published, processing while its videos are transcoded, or failed if one could not be
+	LikeCount      int64            `gorm:"not null;default:0"`          // Number of reactions of any kind, maintained by the like and reaction controllers
+	CommentCount   int64            `gorm:"not null;default:0"`          // Number of comments and replies not hidden, maintained by the comment controllers
+	SaveCount      int64            `gorm:"not null;default:0"`          // Number of saves, maintained by the save controller
+	Comments       []Comment        `gorm:"foreignKey:PostID"`           // List of comments on the post
+	Likes          []Like           `gorm:"foreignKey:PostID"`           // List of likes on the post; not loaded for feeds, which use LikeCount and LikedByMe
+	MyReaction     string           `gorm:"-"`                           // The viewer's reaction, filled in per request
+	LikedByMe      bool             `gorm:"-"`                           // Whether the viewer reacted to the post, filled in per request
+	Reactions      map[string]int64 `gorm:"-"`                           // Reaction counts by emoji, filled in per request
+	Hashtags       []Hashtag        `gorm:"many2many:post_hashtags"`     // Hashtags parsed from the caption and description
+	Mentions       []Mention        `gorm:"foreignKey:PostID"`           // Users @mentioned in the caption
+	MediaID        *uint            `gorm:"uniqueIndex"`                 // Deprecated: the media of the first item, kept for older clients
+	Media          *Media           `gorm:"foreignKey:MediaID"`          // Deprecated: see Items
+	Items          []PostMedia      `gorm:"foreignKey:PostID"`           // Images and videos of the post in display order, up to MaxPostMedia
+}
+
+// Post audiences, from widest to narrowest
+const (
+	AudiencePublic       = "public"
+	AudienceFollowers    = "followers"
+	AudienceCloseFriends = "close_friends"
+)
+
+// Comment policies, from most to least open. Followers means accounts that
+// follow the author; following means accounts the author follows.
+const (
+	CommentPolicyEveryone  = "everyone"
+	CommentPolicyFollowers = "followers"
+	CommentPolicyFollowing = "following"
+	CommentPolicyOff       = "off"
+)
+
+// MaxPinnedComments is how many comments a post's author may pin
+const MaxPinnedComments = 3
+
+// NormalizeCommentPolicy opens comments to everyone when no policy is set,
+// keeps the legacy AllowComments flag in sync, and reports whether the
+// policy is valid. Clients that turn comments off with the legacy flag must
+// have it applied first, as a false flag cannot be told from a missing one.
+func (post *Post) NormalizeCommentPolicy() bool {
+	if post.CommentPolicy == "" {
+		post.CommentPolicy = CommentPolicyEveryone
+	}
+
+	switch post.CommentPolicy {
+	case CommentPolicyEveryone, CommentPolicyFollowers, CommentPolicyFollowing, CommentPolicyOff:
+		post.AllowComments = post.CommentPolicy != CommentPolicyOff
+		return true
+	}
+	return false
+}
+
+// NormalizeAudience fills in the audience from the legacy IsPrivate flag when
+// it is missing, keeps IsPrivate in sync, and reports whether the audience is valid
+func (post *Post) NormalizeAudience() bool {
+	if post.Audience == "" {
+		post.Audience = AudiencePublic
+		if post.IsPrivate {
+			post.Audience = AudienceFollowers
+		}
+	}
+
+	switch post.Audience {
+	case AudiencePublic, AudienceFollowers, AudienceCloseFriends:
+		post.IsPrivate = post.Audience != AudiencePublic
+		return true
+	}
+	return false
 }
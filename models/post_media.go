@@ -0,0 +1,29 @@
+package models
+
+// MaxPostMedia is how many images and videos a carousel post can hold
+const MaxPostMedia = 10
+
+// PostMedia is one image or video of a post, shown in Position order. Posts
+// made before uploads existed have items with a URL and no Media.
+type PostMedia struct {
+	ID       uint           `gorm:"primaryKey"`
+	PostID   uint           `gorm:"not null;uniqueIndex:idx_post_position"`
+	Position int            `gorm:"not null;uniqueIndex:idx_post_position"` // 0 for the cover image
+	MediaID  *uint          `gorm:"uniqueIndex"`                            // Uploaded image or video; each upload appears in one post only
+	Media    *Media         `gorm:"foreignKey:MediaID"`
+	URL      string         `gorm:"not null"`   // Full-size image or largest video URL, copied from Media when there is one; empty while a video processes
+	AltText  string         `gorm:"default:''"` // Description for screen readers
+	Width    int            `gorm:"default:0"`  // Of the upright image or video, in pixels; 0 when unknown
+	Height   int            `gorm:"default:0"`
+	Tags     []PostMediaTag `gorm:"foreignKey:PostMediaID"` // Users tagged in the image
+}
+
+// PostMediaTag marks where in an image a user appears
+type PostMediaTag struct {
+	ID          uint    `gorm:"primaryKey"`
+	PostMediaID uint    `gorm:"not null;uniqueIndex:idx_item_user"`
+	UserID      uint    `gorm:"not null;uniqueIndex:idx_item_user;index"`
+	User        *User   `gorm:"foreignKey:UserID"`
+	X           float64 `gorm:"not null;default:0"` // From the left edge, 0 to 1
+	Y           float64 `gorm:"not null;default:0"` // From the top edge, 0 to 1
+}
@@ -0,0 +1,14 @@
+package models
+
+// Relationship describes how the viewer relates to another user. It is
+// computed per request and never stored.
+type Relationship struct {
+	UserID          uint // The other user
+	Following       bool // The viewer follows the user
+	FollowedBy      bool // The user follows the viewer
+	Requested       bool // The viewer has a pending follow request to the user
+	IncomingRequest bool // The user has a pending follow request to the viewer
+	Blocked         bool // The viewer has blocked the user
+	Muted           bool // The viewer has muted the user
+	CloseFriend     bool // The user is on the viewer's close friends list
+}
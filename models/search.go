@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ftsTable describes an SQLite FTS5 index kept in sync with a content table
+type ftsTable struct {
+	Name    string
+	Source  string
+	Columns []string
+}
+
+// searchFTSTables are the SQLite full-text indexes used by search
+var searchFTSTables = []ftsTable{
+	{Name: "users_fts", Source: "users", Columns: []string{"username", "full_name"}},
+	{Name: "posts_fts", Source: "posts", Columns: []string{"caption", "description"}},
+}
+
+// PrepareSearch creates the indexes behind search and autocomplete. It runs
+// after AutoMigrate and is idempotent. Both databases get prefix indexes on
+// lowercased usernames and full names. Postgres also gets full-text and
+// trigram indexes; SQLite gets FTS5 tables maintained by triggers when the
+// FTS5 module is compiled in (go build -tags sqlite_fts5), and search falls
+// back to LIKE matching otherwise.
+func PrepareSearch(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		statements := []string{
+			"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+			"CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN (to_tsvector('simple', username || ' ' || full_name))",
+			"CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING GIN (username gin_trgm_ops)",
+			"CREATE INDEX IF NOT EXISTS idx_users_full_name_trgm ON users USING GIN (full_name gin_trgm_ops)",
+			"CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (to_tsvector('simple', caption || ' ' || COALESCE(description, '')))",
+			"CREATE INDEX IF NOT EXISTS idx_hashtags_name_trgm ON hashtags USING GIN (name gin_trgm_ops)",
+			"CREATE INDEX IF NOT EXISTS idx_users_username_prefix ON users (LOWER(username) text_pattern_ops)",
+			"CREATE INDEX IF NOT EXISTS idx_users_full_name_prefix ON users (LOWER(full_name) text_pattern_ops)",
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+	case "sqlite":
+		statements := []string{
+			"CREATE INDEX IF NOT EXISTS idx_users_username_prefix ON users (LOWER(username))",
+			"CREATE INDEX IF NOT EXISTS idx_users_full_name_prefix ON users (LOWER(full_name))",
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		for _, table := range searchFTSTables {
+			if err := createFTSTable(db, table); err != nil {
+				if strings.Contains(err.Error(), "no such module") {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createFTSTable creates an external content FTS5 table over table.Source,
+// the triggers that keep it current and rebuilds it from the existing rows
+func createFTSTable(db *gorm.DB, table ftsTable) error {
+	columns := strings.Join(table.Columns, ", ")
+	newValues := "new." + strings.Join(table.Columns, ", new.")
+	oldValues := "old." + strings.Join(table.Columns, ", old.")
+	deleteRow := "INSERT INTO " + table.Name + "(" + table.Name + ", rowid, " + columns + ") VALUES ('delete', old.id, " + oldValues + ");"
+	insertRow := "INSERT INTO " + table.Name + "(rowid, " + columns + ") VALUES (new.id, " + newValues + ");"
+
+	statements := []string{
+		"CREATE VIRTUAL TABLE IF NOT EXISTS " + table.Name + " USING fts5(" + columns + ", content='" + table.Source + "', content_rowid='id')",
+		"CREATE TRIGGER IF NOT EXISTS " + table.Name + "_ai AFTER INSERT ON " + table.Source + " BEGIN " + insertRow + " END",
+		"CREATE TRIGGER IF NOT EXISTS " + table.Name + "_ad AFTER DELETE ON " + table.Source + " BEGIN " + deleteRow + " END",
+		"CREATE TRIGGER IF NOT EXISTS " + table.Name + "_au AFTER UPDATE ON " + table.Source + " BEGIN " + deleteRow + " " + insertRow + " END",
+		"INSERT INTO " + table.Name + "(" + table.Name + ") VALUES ('rebuild')",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// SuggestionDismissal records that a user does not want another user suggested to them again
+type SuggestionDismissal struct {
+	ID          uint      `gorm:"primaryKey"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_user_dismissed"` // User who dismissed the suggestion
+	DismissedID uint      `gorm:"not null;uniqueIndex:idx_user_dismissed"` // User who should no longer be suggested
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// HashtagUsage counts how many published posts were tagged with a hashtag
+// during one hour. Trending compares recent hours against older ones.
+type HashtagUsage struct {
+	ID        uint      `gorm:"primaryKey"`
+	HashtagID uint      `gorm:"not null;uniqueIndex:idx_hashtag_hour"`
+	Hour      time.Time `gorm:"not null;uniqueIndex:idx_hashtag_hour;index"` // Start of the hour, in UTC
+	Uses      int64     `gorm:"not null;default:0"`
+}
+
+// TrendingHashtag is one row of the latest trending computation, written by
+// the trending job and read by the explore tab
+type TrendingHashtag struct {
+	ID           uint      `gorm:"primaryKey"`
+	Rank         int       `gorm:"not null;index"` // 1 is the most trending
+	HashtagID    uint      `gorm:"not null"`
+	Hashtag      *Hashtag  `gorm:"foreignKey:HashtagID"`
+	Score        float64   `gorm:"not null"`
+	WindowUses   int64     `gorm:"not null"` // Uses during the trending window
+	BaselineRate float64   `gorm:"not null"` // Average uses per hour before the window
+	ComputedAt   time.Time `gorm:"not null"`
+}
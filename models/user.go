@@ -10,20 +10,43 @@ import (
 
 // User represents a user record in the database
 type User struct {
-	ID           uint      `gorm:"primaryKey"`
-	FullName     string    `gorm:"not null"`
-	Username     string    `gorm:"unique;not null"`
-	Email        string    `gorm:"unique;not null"`
-	Password     string    `gorm:"not null"`
-	Bio          string    `gorm:"default:''"`
-	ProfileImage string    `gorm:"default:''"`
-	Posts        []Post    `gorm:"foreignKey:UserID"`
-	Saves        []Save    `gorm:"foreignKey:UserID"`
-	Likes        []Like    `gorm:"foreignKey:UserID"`
-	Followees    []Follow  `gorm:"foreignKey:FollowingID"` // Use Follow as the model here
-	Followings   []Follow  `gorm:"foreignKey:FollowerID"`  // Use Follow as the model here
-	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	ID             uint          `gorm:"primaryKey"`
+	FullName       string        `gorm:"not null"`
+	Username       string        `gorm:"unique;not null"`
+	Email          string        `gorm:"unique;not null"`
+	Password       string        `gorm:"not null"`
+	Bio            string        `gorm:"default:''"`
+	ProfileImage   string        `gorm:"default:''"`
+	IsPrivate      bool          `gorm:"default:false"`               // Private accounts approve followers and hide their content from everyone else
+	MentionPolicy  string        `gorm:"not null;default:'everyone'"` // Who can @mention the user: everyone, following or nobody
+	HideOffensive  bool          `gorm:"default:true"`                // Hide comments on the user's posts that match the built-in offensive word list; on unless the user opts out
+	Posts          []Post        `gorm:"foreignKey:UserID"`
+	Saves          []Save        `gorm:"foreignKey:UserID"`
+	Likes          []Like        `gorm:"foreignKey:UserID"`
+	Followees      []Follow      `gorm:"foreignKey:FollowingID"` // Use Follow as the model here
+	Followings     []Follow      `gorm:"foreignKey:FollowerID"`  // Use Follow as the model here
+	FollowerCount  int64         `gorm:"not null;default:0"`     // Number of users following this user
+	FollowingCount int64         `gorm:"not null;default:0"`     // Number of users this user follows
+	PostCount      int64         `gorm:"not null;default:0"`     // Number of published posts
+	Relationship   *Relationship `gorm:"-" json:",omitempty"`    // The viewer's relationship to this user, filled in on profile responses
+	CreatedAt      time.Time     `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time     `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Mention policies: who may @mention a user
+const (
+	MentionPolicyEveryone  = "everyone"
+	MentionPolicyFollowing = "following" // Only accounts the user follows
+	MentionPolicyNobody    = "nobody"
+)
+
+// ValidMentionPolicy reports whether policy is a known mention policy
+func ValidMentionPolicy(policy string) bool {
+	switch policy {
+	case MentionPolicyEveryone, MentionPolicyFollowing, MentionPolicyNobody:
+		return true
+	}
+	return false
 }
 
 // HashPassword hashes the user's password before storing it
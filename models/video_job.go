@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// VideoJob is the queued processing of an uploaded video: probing it,
+// extracting a poster frame and transcoding it to streaming renditions.
+// Failed attempts are retried with a growing delay until MaxAttempts.
+type VideoJob struct {
+	ID          uint       `gorm:"primaryKey"`
+	MediaID     uint       `gorm:"not null;uniqueIndex"`
+	Status      string     `gorm:"not null;default:'queued';index:idx_video_job_due"` // See the video job statuses
+	RunAt       time.Time  `gorm:"not null;index:idx_video_job_due"`                  // Earliest time the next attempt may start
+	Attempts    int        `gorm:"not null;default:0"`
+	MaxAttempts int        `gorm:"not null;default:3"`
+	StartedAt   *time.Time // Of the current or last attempt
+	LastError   string     `gorm:"default:''"`
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time
+}
+
+// Video job statuses
+const (
+	VideoJobQueued  = "queued"
+	VideoJobRunning = "running"
+	VideoJobDone    = "done"
+	VideoJobFailed  = "failed"
+)
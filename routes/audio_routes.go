@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AudioRoutes registers the routes for uploading voice comment recordings
+func AudioRoutes(r *gin.Engine) {
+	audioGroup := r.Group("/audio")
+
+	// Uploads always belong to the logged in user, and need storage to go to
+	audioGroup.Use(middleware.AuthRequired(), middleware.StorageRequired())
+
+	// Route to upload a recording as multipart field "audio"; pass the returned ID as a comment's AudioID
+	audioGroup.POST("", controllers.UploadAudio)
+
+	// Route to discard an upload that was never attached to a comment
+	audioGroup.DELETE("/:id", controllers.DeleteAudio)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockRoutes registers the routes for blocking users
+func BlockRoutes(r *gin.Engine) {
+	blockGroup := r.Group("/blocks")
+
+	// Every block route acts on behalf of the logged in user
+	blockGroup.Use(middleware.AuthRequired())
+
+	// Route to list the users the logged in user has blocked
+	blockGroup.GET("", controllers.GetBlocks)
+
+	// Route to block a user
+	blockGroup.POST("", controllers.CreateBlock)
+
+	// Route to unblock a user by their user ID
+	blockGroup.DELETE("/:userID", controllers.DeleteBlock)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CloseFriendRoutes registers the routes for managing the close friends list
+func CloseFriendRoutes(r *gin.Engine) {
+	closeFriendGroup := r.Group("/close-friends")
+
+	// Every close friends route acts on the logged in user's list
+	closeFriendGroup.Use(middleware.AuthRequired())
+
+	// Route to list the logged in user's close friends
+	closeFriendGroup.GET("", controllers.GetCloseFriends)
+
+	// Route to add a user to close friends
+	closeFriendGroup.POST("", controllers.CreateCloseFriend)
+
+	// Route to remove a user from close friends by their user ID
+	closeFriendGroup.DELETE("/:userID", controllers.DeleteCloseFriend)
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommentFilterRoutes registers the routes for filtering comments on the logged in user's posts
+func CommentFilterRoutes(r *gin.Engine) {
+	filterGroup := r.Group("/comment-filters")
+
+	// Every filter route acts on behalf of the logged in user
+	filterGroup.Use(middleware.AuthRequired())
+
+	// Route to list the user's filter phrases
+	filterGroup.GET("", controllers.GetCommentFilters)
+
+	// Route to add a word or phrase to filter
+	filterGroup.POST("", controllers.CreateCommentFilter)
+
+	// Route to switch the built-in offensive word list on or off
+	filterGroup.PUT("/offensive", controllers.SetHideOffensive)
+
+	// Route to remove a filter by ID
+	filterGroup.DELETE("/:id", controllers.DeleteCommentFilter)
+
+	// Route to review hidden comments on the user's posts; unhide them with DELETE /comment/:commentID/hide
+	filterGroup.GET("/hidden", controllers.GetHiddenComments)
+}
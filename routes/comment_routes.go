@@ -15,11 +15,27 @@ func CommentRoutes(router *gin.Engine) {
 	commentGroup.POST("", middleware.AuthRequired(), controllers.CreateComment)
 
 	// GET route to retrieve all comments for a specific post
-	commentGroup.GET("", controllers.GetCommentsByPostID)
+	commentGroup.GET("", middleware.AuthOptional(), controllers.GetCommentsByPostID)
 
-	// PATCH route to update an existing comment by ID
-	router.PATCH("/comment/:commentID", controllers.UpdateComment)
+	// GET route to page through the replies on a comment
+	router.GET("/comment/:commentID/replies", middleware.AuthOptional(), controllers.GetRepliesByCommentID)
 
-	// DELETE route to remove a comment by ID
-	router.DELETE("/comment/:commentID", controllers.UpdateComment)
+	// GET route to read a comment with its nested replies
+	router.GET("/comment/:commentID/thread", middleware.AuthOptional(), controllers.GetCommentThread)
+
+	// POST and DELETE routes to like and unlike a comment or reply; other reactions live in the reaction routes
+	router.POST("/comment/:commentID/likes", middleware.AuthRequired(), controllers.LikeComment)
+	router.DELETE("/comment/:commentID/likes", middleware.AuthRequired(), controllers.UnlikeComment)
+
+	// PATCH route to update an existing comment by ID, as the commenter
+	router.PATCH("/comment/:commentID", middleware.AuthRequired(), controllers.UpdateComment)
+
+	// DELETE route to remove a comment by ID, as the commenter or the post's author
+	router.DELETE("/comment/:commentID", middleware.AuthRequired(), controllers.DeleteComment)
+
+	// Post authors' moderation tools: pin comments to the top, hide them from others
+	router.POST("/comment/:commentID/pin", middleware.AuthRequired(), controllers.PinComment)
+	router.DELETE("/comment/:commentID/pin", middleware.AuthRequired(), controllers.UnpinComment)
+	router.POST("/comment/:commentID/hide", middleware.AuthRequired(), controllers.HideComment)
+	router.DELETE("/comment/:commentID/hide", middleware.AuthRequired(), controllers.UnhideComment)
 }
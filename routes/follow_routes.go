@@ -15,13 +15,27 @@ func FollowRoutes(r *gin.Engine) {
 	// Use the authentication middleware for routes that require user authentication
 	// followerGroup.Use(middleware.AuthRequired())
 
-	r.GET("followers/:id", controllers.GetFollowersByUserID)
+	r.GET("followers/:id", middleware.AuthOptional(), controllers.GetFollowersByUserID)
 
-	r.GET("followings/:id", controllers.GetFollowingsByUserID)
+	r.GET("followings/:id", middleware.AuthOptional(), controllers.GetFollowingsByUserID)
 
 	// Route to create a new follower
 	followGroup.POST("", middleware.AuthRequired(), controllers.CreateAFollow)
 
 	// Route to delete a follower by ID
 	followGroup.DELETE("/:id", middleware.AuthRequired(), controllers.DeleteFollower)
+
+	// Routes for private accounts to manage incoming follow requests
+	followGroup.GET("/requests", middleware.AuthRequired(), controllers.GetFollowRequests)
+	followGroup.GET("/requests/sent", middleware.AuthRequired(), controllers.GetSentFollowRequests)
+	followGroup.POST("/requests/:id/approve", middleware.AuthRequired(), controllers.ApproveFollowRequest)
+	followGroup.DELETE("/requests/:id", middleware.AuthRequired(), controllers.RejectFollowRequest)
+
+	// Routes to manage follows by user ID rather than follow record ID
+	followGroup.GET("/users/:userID", middleware.AuthRequired(), controllers.GetFollowByUserID)
+	followGroup.DELETE("/users/:userID", middleware.AuthRequired(), controllers.UnfollowUser)
+	followGroup.DELETE("/followers/:userID", middleware.AuthRequired(), controllers.RemoveFollower)
+
+	// Route to follow and unfollow many users at once
+	followGroup.POST("/bulk", middleware.AuthRequired(), controllers.BulkFollow)
 }
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HashtagRoutes registers the routes for hashtag pages and hashtag follows
+func HashtagRoutes(r *gin.Engine) {
+	tagGroup := r.Group("/tags")
+
+	// Route to list the hashtags the logged in user follows
+	tagGroup.GET("/following", middleware.AuthRequired(), controllers.GetFollowedHashtags)
+
+	// Route to get a hashtag and its post count
+	tagGroup.GET("/:name", middleware.AuthOptional(), controllers.GetHashtag)
+
+	// Route to list posts tagged with a hashtag
+	tagGroup.GET("/:name/posts", middleware.AuthOptional(), controllers.GetHashtagPosts)
+
+	// Route to follow a hashtag
+	tagGroup.POST("/:name/follow", middleware.AuthRequired(), controllers.FollowHashtag)
+
+	// Route to unfollow a hashtag
+	tagGroup.DELETE("/:name/follow", middleware.AuthRequired(), controllers.UnfollowHashtag)
+}
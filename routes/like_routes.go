@@ -17,6 +17,6 @@ func LikeRoutes(router *gin.Engine) {
 	// POST route to create a new like on a specific post
 	likeGroup.POST("", middleware.AuthRequired(), controllers.CreateLike)
 
-	// DELETE route to remove a like by ID
-	router.DELETE("/:postID/:userID", controllers.DeleteLike)
+	// DELETE route to remove the logged in user's like; the user ID must be their own
+	router.DELETE("/:postID/:userID", middleware.AuthRequired(), controllers.DeleteLike)
 }
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"pixi/config"
+	"pixi/controllers"
+	"pixi/middleware"
+	"pixi/storage"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MediaRoutes registers the routes for uploading post images, and serves
+// uploaded files when they are kept on local disk under a path of this
+// server. Other backends serve their files themselves.
+func MediaRoutes(r *gin.Engine) {
+	mediaGroup := r.Group("/media")
+
+	// Uploads always belong to the logged in user, and need storage to go to
+	mediaGroup.Use(middleware.AuthRequired(), middleware.StorageRequired())
+
+	// Route to upload an image as multipart field "file"; pass the returned ID as a post's MediaID
+	mediaGroup.POST("", controllers.UploadMedia)
+
+	// Route to get a URL to upload an image straight to storage, when the backend supports it
+	mediaGroup.POST("/uploads", controllers.CreateMediaUpload)
+
+	// Route to finish a direct upload once the file has been sent
+	mediaGroup.POST("/:id/complete", controllers.CompleteMediaUpload)
+
+	// Route to get one of the user's uploads
+	mediaGroup.GET("/:id", controllers.GetMedia)
+
+	// Route to discard an upload that no post uses
+	mediaGroup.DELETE("/:id", controllers.DeleteMedia)
+
+	local, ok := config.GetStorage().(*storage.Local)
+	if !ok || !strings.HasPrefix(local.BaseURL, "/") {
+		return
+	}
+	r.Static(strings.TrimRight(local.BaseURL, "/"), local.Dir)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MuteRoutes registers the routes for muting users
+func MuteRoutes(r *gin.Engine) {
+	muteGroup := r.Group("/mutes")
+
+	// Every mute route acts on behalf of the logged in user
+	muteGroup.Use(middleware.AuthRequired())
+
+	// Route to list the users the logged in user has muted
+	muteGroup.GET("", controllers.GetMutes)
+
+	// Route to mute a user or change what is muted
+	muteGroup.POST("", controllers.CreateMute)
+
+	// Route to unmute a user by their user ID
+	muteGroup.DELETE("/:userID", controllers.DeleteMute)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationRoutes registers the routes for the logged in user's notifications
+func NotificationRoutes(r *gin.Engine) {
+	notificationGroup := r.Group("/notifications")
+
+	// Notifications are personal to the logged in user
+	notificationGroup.Use(middleware.AuthRequired())
+
+	// Route to list notifications, newest first
+	notificationGroup.GET("", controllers.GetNotifications)
+
+	// Route to mark notifications as read
+	notificationGroup.POST("/read", controllers.MarkNotificationsRead)
+}
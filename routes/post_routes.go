@@ -11,7 +11,7 @@ func PostRoutes(router *gin.Engine) {
 	postGroup := router.Group("/posts")
 
 	// Add GET routes for retrieving posts
-	postGroup.GET("", controllers.GetPost)
+	postGroup.GET("", middleware.AuthOptional(), controllers.GetPost)
 
 	// Add POST route for creating a new post
 	postGroup.POST("", middleware.AuthRequired(), controllers.CreatePost)
@@ -20,11 +20,11 @@ func PostRoutes(router *gin.Engine) {
 	postGroup.POST("/create-scheduled-post", middleware.AuthRequired(), controllers.CreateScheduledPost)
 
 	// Add PATCH route for updating an existing post
-	router.PATCH("/post/:id", controllers.UpdatePost)
+	router.PATCH("/post/:id", middleware.AuthRequired(), controllers.UpdatePost)
 
 	// Add GET route for retrieving a post by ID
-	router.GET("/post/:id", controllers.GetPostByID)
+	router.GET("/post/:id", middleware.AuthOptional(), controllers.GetPostByID)
 
-	// Add DELETE route for deleting a post by ID
-	postGroup.DELETE("/:id", controllers.DeletePost)
+	// Add DELETE route for deleting a post by ID; only its author may
+	postGroup.DELETE("/:id", middleware.AuthRequired(), controllers.DeletePost)
 }
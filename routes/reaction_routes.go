@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReactionRoutes registers the routes for emoji reactions on posts and comments
+func ReactionRoutes(r *gin.Engine) {
+	// Route to list the emoji users may react with
+	r.GET("/reactions", controllers.GetReactionSet)
+
+	// PUT sets or changes the logged in user's reaction, DELETE removes it
+	r.PUT("/post/:id/reactions", middleware.AuthRequired(), controllers.SetPostReaction)
+	r.DELETE("/post/:id/reactions", middleware.AuthRequired(), controllers.DeletePostReaction)
+	r.PUT("/comment/:commentID/reactions", middleware.AuthRequired(), controllers.SetCommentReaction)
+	r.DELETE("/comment/:commentID/reactions", middleware.AuthRequired(), controllers.DeleteCommentReaction)
+}
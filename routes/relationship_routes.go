@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelationshipRoutes registers the routes describing how the logged in user relates to others
+func RelationshipRoutes(r *gin.Engine) {
+	relationshipGroup := r.Group("/relationships")
+
+	// Relationships are always relative to the logged in user
+	relationshipGroup.Use(middleware.AuthRequired())
+
+	// Route to fetch relationships in batch, e.g. /relationships?ids=1,2,3
+	relationshipGroup.GET("", controllers.GetRelationships)
+
+	// Route to fetch the relationship to a single user
+	relationshipGroup.GET("/:userID", controllers.GetRelationship)
+}
@@ -7,22 +7,18 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RepliesRoutes defines the routes for handling reply-related operations
+// ReplyRoutes keeps the reply routes from before threaded comments working
+// for older clients; new clients use the comment routes
 func ReplyRoutes(router *gin.Engine) {
-	replyGroup := router.Group("/replies")
+	// POST route to reply to a comment
+	router.POST("/replies", middleware.AuthRequired(), controllers.CreateReply)
 
-	// apily authentication middleware to all routes in this group
-	// replyGroup.Use(middleware.AuthRequired())
+	// GET route to retrieve all replies on a post
+	router.GET("/:postID/replies", middleware.AuthOptional(), controllers.GetRepliesByPostID)
 
-	// POST route to create a new reply on a specific comment
-	replyGroup.POST("", middleware.AuthRequired(), controllers.CreateReply)
+	// PATCH route to update a reply by ID, as its commenter
+	router.PATCH("/reply/:replyID", middleware.AuthRequired(), controllers.UpdateReply)
 
-	// GET route to retrieve all replies by comment ID
-	router.GET("/:postID/replies", controllers.GetRepliesByPostID)
-
-	// PATCH route to update an existing reply by ID
-	router.PATCH("/reply/:replyID", controllers.UpdateReply)
-
-	// DELETE route to remove a reply by ID
-	router.DELETE("/reply/:replyID", controllers.DeleteReply)
+	// DELETE route to remove a reply by ID, as its commenter or the post's author
+	router.DELETE("/reply/:replyID", middleware.AuthRequired(), controllers.DeleteReply)
 }
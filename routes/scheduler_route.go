@@ -3,13 +3,38 @@ package routes
 import (
 	"net/http"
 	"pixi/controllers"
+	"pixi/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
+// SchedulerRoutes registers the routes the Vercel cron jobs call
 func SchedulerRoutes(r *gin.Engine) {
-	r.GET("/trigger-scheduler", func(c *gin.Context) {
+	// Only the cron jobs may trigger the background tasks
+	cron := r.Group("/", middleware.CronRequired())
+
+	cron.GET("/trigger-scheduler", func(c *gin.Context) {
 		controllers.PublishScheduledPosts() // Manually trigger the function
 		c.JSON(http.StatusOK, gin.H{"message": "Scheduler triggered successfully"})
 	})
+
+	cron.GET("/trigger-reconcile-counters", func(c *gin.Context) {
+		controllers.ReconcileCounters() // Repair drift in the denormalized counters
+		c.JSON(http.StatusOK, gin.H{"message": "Counter reconciliation triggered successfully"})
+	})
+
+	cron.GET("/trigger-trending-hashtags", func(c *gin.Context) {
+		controllers.RecomputeTrendingHashtags() // Refresh the trending hashtags table
+		c.JSON(http.StatusOK, gin.H{"message": "Trending hashtags recomputed successfully"})
+	})
+
+	cron.GET("/trigger-audio-cleanup", func(c *gin.Context) {
+		controllers.CleanupOrphanedAudio() // Remove voice recordings never attached to a comment
+		c.JSON(http.StatusOK, gin.H{"message": "Audio cleanup triggered successfully"})
+	})
+
+	cron.GET("/trigger-media-cleanup", func(c *gin.Context) {
+		controllers.CleanupOrphanedMedia() // Remove post images never used in a post
+		c.JSON(http.StatusOK, gin.H{"message": "Media cleanup triggered successfully"})
+	})
 }
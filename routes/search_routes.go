@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchRoutes registers the search route
+func SearchRoutes(r *gin.Engine) {
+	// Route to search users, posts and hashtags; results are filtered for the logged in user when there is one
+	r.GET("/search", middleware.AuthOptional(), controllers.Search)
+}
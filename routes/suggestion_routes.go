@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"pixi/controllers"
+	"pixi/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SuggestionRoutes registers the routes for follow suggestions
+func SuggestionRoutes(r *gin.Engine) {
+	suggestionGroup := r.Group("/suggestions")
+
+	// Suggestions are personal to the logged in user
+	suggestionGroup.Use(middleware.AuthRequired())
+
+	// Route to list suggested accounts to follow
+	suggestionGroup.GET("", controllers.GetSuggestions)
+
+	// Route to stop suggesting a user
+	suggestionGroup.POST("/:userID/dismiss", controllers.DismissSuggestion)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"pixi/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrendingRoutes registers the routes for the explore tab's trending lists
+func TrendingRoutes(r *gin.Engine) {
+	trendingGroup := r.Group("/trending")
+
+	// Route to list the top trending hashtags
+	trendingGroup.GET("/hashtags", controllers.GetTrendingHashtags)
+}
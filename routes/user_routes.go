@@ -3,6 +3,7 @@ package routes
 
 import (
 	"pixi/controllers"
+	"pixi/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,13 +17,16 @@ func UserRoutes(router *gin.Engine) {
 	// Add POST route for creating a new user
 	router.POST("/register", controllers.CreateUser)
 
-	userGroup.GET("", controllers.GetUsers)
+	userGroup.GET("", middleware.AuthOptional(), controllers.GetUsers)
+
+	// Add GET route for typeahead suggestions by username or full name prefix
+	userGroup.GET("/autocomplete", middleware.AuthOptional(), controllers.AutocompleteUsers)
 
 	// Add GET route for retrieving a user by ID
-	userGroup.GET("/:id", controllers.GetUser) // Assuming you pass the user ID as a URL parameter
+	userGroup.GET("/:id", middleware.AuthOptional(), controllers.GetUser) // Assuming you pass the user ID as a URL parameter
 
 	// Add PATCH route for updating a user
-	userGroup.PATCH("/:id", controllers.UpdateUser) // Assuming you pass the user ID as a URL parameter
+	userGroup.PATCH("/:id", middleware.AuthRequired(), controllers.UpdateUser) // Assuming you pass the user ID as a URL parameter
 
 	// Add DELETE route for deleting a user by ID
 	userGroup.DELETE("/:id", controllers.DeleteUser) // Assuming you pass the user ID as a URL parameter
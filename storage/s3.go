@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 stores files in a bucket of Amazon S3 or a compatible service such as
+// MinIO or Cloudflare R2. Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	Endpoint  string // Service URL, such as https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Region    string // Signing region; compatible services usually accept us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+
+	// PathStyle addresses objects as Endpoint/Bucket/key instead of
+	// Bucket.Endpoint/key, which most self-hosted services need
+	PathStyle bool
+
+	// PublicURL, when set, is the prefix clients download objects from, such
+	// as a CDN in front of the bucket; otherwise objects are linked directly
+	PublicURL string
+
+	// Client sends the requests; http.DefaultClient when nil
+	Client *http.Client
+}
+
+// unsignedPayload skips hashing request bodies, which are streamed
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// clock returns the time requests are signed at
+var clock = time.Now
+
+// objectURL is the address of key in the bucket
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	endpoint, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint %q", s.Endpoint)
+	}
+	if s.PathStyle {
+		endpoint.Path += "/" + s.Bucket
+	} else {
+		endpoint.Host = s.Bucket + "." + endpoint.Host
+	}
+	endpoint.Path += "/" + strings.TrimLeft(key, "/")
+	endpoint.RawPath = uriEncode(endpoint.Path, false)
+	return endpoint, nil
+}
+
+// do sends a signed request for key with the given headers, turning error
+// statuses into errors
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, clock().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage: S3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// Put uploads the object in a single request
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, http.Header{"Content-Type": {contentType}})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Get downloads the object
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object; S3 reports success for missing keys too
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// URL links to the object under PublicURL, or in the bucket itself
+func (s *S3) URL(key string) string {
+	if s.PublicURL != "" {
+		return strings.TrimRight(s.PublicURL, "/") + "/" + uriEncode(strings.TrimLeft(key, "/"), false)
+	}
+	target, err := s.objectURL(key)
+	if err != nil {
+		return ""
+	}
+	return target.String()
+}
+
+// PresignPut returns a URL that accepts a PUT of the object with the given
+// content type, without credentials, until it expires
+func (s *S3) PresignPut(key, contentType string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodPut, key, http.Header{"Content-Type": {contentType}}, clock().UTC(), expires)
+}
+
+// presign returns a URL for a request made at now that must carry the given
+// headers, signing them in the query instead of an Authorization header
+func (s *S3) presign(method, key string, header http.Header, now time.Time, expires time.Duration) (string, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	scope := s.scope(now)
+
+	headers := http.Header{"Host": {target.Host}}
+	for name, values := range header {
+		headers[name] = values
+	}
+	canonical, signed := canonicalHeaders(headers)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", signed)
+	target.RawQuery = canonicalQuery(query)
+
+	request := strings.Join([]string{method, target.EscapedPath(), target.RawQuery, canonical, signed, unsignedPayload}, "\n")
+
+	target.RawQuery += "&X-Amz-Signature=" + s.signature(now, scope, request)
+	return target.String(), nil
+}
+
+// sign adds the Authorization header for req, signing its host and every
+// header already set on it. The body is left unsigned unless the request
+// carries its hash in X-Amz-Content-Sha256.
+func (s *S3) sign(req *http.Request, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	payload := req.Header.Get("X-Amz-Content-Sha256")
+	if payload == "" {
+		payload = unsignedPayload
+		req.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	headers := http.Header{"Host": {req.URL.Host}}
+	for name, values := range req.Header {
+		headers[name] = values
+	}
+	canonical, signed := canonicalHeaders(headers)
+	scope := s.scope(now)
+	request := strings.Join([]string{req.Method, req.URL.EscapedPath(), canonicalQuery(req.URL.Query()), canonical, signed, payload}, "\n")
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+s.signature(now, scope, request))
+}
+
+// scope is the credential scope of a request made at now
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request with a key derived from the secret
+func (s *S3) signature(now time.Time, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), now.Format("20060102"))
+	for _, part := range []string{s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalHeaders lists headers as lowercase name:value lines sorted by
+// name, with the names joined by semicolons for SignedHeaders
+func canonicalHeaders(headers http.Header) (string, string) {
+	names := make([]string, 0, len(headers))
+	values := map[string]string{}
+	for name, v := range headers {
+		lower := strings.ToLower(name)
+		names = append(names, lower)
+		values[lower] = strings.Join(strings.Fields(strings.Join(v, ",")), " ")
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalQuery encodes query parameters sorted by name, then value
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, as
+// Signature Version 4 requires; slashes are kept unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+// Package storage saves uploaded media files and hands out the URLs they are served from.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"time"
+)
+
+// ErrNotFound is returned when a key has no stored object
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage is a place to keep uploaded files, addressed by slash separated keys
+type Storage interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the object under key, returning ErrNotFound when it is missing
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object under key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+
+	// URL returns the address clients download the object from
+	URL(key string) string
+}
+
+// Presigner is implemented by backends that let clients upload straight to
+// them, without passing the file through this server
+type Presigner interface {
+	// PresignPut returns a URL that accepts a PUT of key with the given content type until expires passes
+	PresignPut(key, contentType string, expires time.Duration) (string, error)
+}
+
+// NewKey returns a fresh, unguessable key under prefix with the given
+// extension, such as "audio/42/9f86d081884c7d65.mp3"
+func NewKey(prefix, ext string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return path.Join(prefix, hex.EncodeToString(random)+ext), nil
+}
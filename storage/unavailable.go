@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUnavailable is returned by every call to Unavailable
+var ErrUnavailable = errors.New("storage: not configured")
+
+// Unavailable stands in for a backend that could not be set up, so the rest
+// of the API keeps working while uploads fail with ErrUnavailable
+type Unavailable struct {
+	Err error // Why the backend could not be set up
+}
+
+func (u *Unavailable) err() error {
+	return fmt.Errorf("%w: %v", ErrUnavailable, u.Err)
+}
+
+// Put always fails
+func (u *Unavailable) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	return u.err()
+}
+
+// Get always fails
+func (u *Unavailable) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, u.err()
+}
+
+// Delete always fails
+func (u *Unavailable) Delete(ctx context.Context, key string) error {
+	return u.err()
+}
+
+// URL has nothing to link to
+func (u *Unavailable) URL(key string) string {
+	return ""
+}
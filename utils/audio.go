@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+)
+
+// Audio formats accepted for voice comments
+const (
+	AudioFormatWAV = "wav"
+	AudioFormatMP3 = "mp3"
+	AudioFormatOGG = "ogg"
+	AudioFormatM4A = "m4a"
+)
+
+// ErrUnsupportedAudio is returned for files that are not WAV, MP3, Ogg
+// (Opus or Vorbis) or M4A audio
+var ErrUnsupportedAudio = errors.New("unsupported audio format")
+
+// ErrCorruptAudio is returned for files that look like a supported format but cannot be parsed
+var ErrCorruptAudio = errors.New("audio file is corrupt or truncated")
+
+// AudioInfo describes an uploaded audio file
+type AudioInfo struct {
+	Format      string
+	ContentType string
+	Duration    time.Duration
+	Peaks       []int // Loudness per time slice, 0-100, for drawing a waveform
+}
+
+// ProbeAudio detects the format of data from its contents, measures its
+// duration and computes peakCount waveform peaks. No decoder is available, so
+// only WAV peaks are true sample peaks: MP3 peaks follow each frame's global
+// gain, and Ogg and M4A peaks follow packet sizes, which track loudness
+// closely enough for a waveform in variable bitrate speech.
+func ProbeAudio(data []byte, peakCount int) (AudioInfo, error) {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return probeWAV(data, peakCount)
+	case len(data) >= 4 && string(data[0:4]) == "OggS":
+		return probeOGG(data, peakCount)
+	case len(data) >= 8 && string(data[4:8]) == "ftyp":
+		return probeM4A(data, peakCount)
+	case len(data) >= 3 && (string(data[0:3]) == "ID3" || data[0] == 0xFF && data[1]&0xE0 == 0xE0):
+		return probeMP3(data, peakCount)
+	}
+	return AudioInfo{}, ErrUnsupportedAudio
+}
+
+// peakBuckets accumulates values into equal time slices
+type peakBuckets struct {
+	max   []float64
+	sum   []float64
+	count []int
+}
+
+func newPeakBuckets(n int) *peakBuckets {
+	return &peakBuckets{max: make([]float64, n), sum: make([]float64, n), count: make([]int, n)}
+}
+
+// add records value at position, a fraction of the total duration in [0, 1]
+func (b *peakBuckets) add(position, value float64) {
+	if len(b.max) == 0 {
+		return
+	}
+	i := int(position * float64(len(b.max)))
+	if i >= len(b.max) {
+		i = len(b.max) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	if value > b.max[i] {
+		b.max[i] = value
+	}
+	b.sum[i] += value
+	b.count[i]++
+}
+
+// peaks scales the per-slice maxima, or means when useMean is set, to 0-100
+// relative to the loudest slice. floor is subtracted first so that formats
+// whose quiet parts are not near zero still show contrast.
+func (b *peakBuckets) peaks(useMean bool, floor float64) []int {
+	values := make([]float64, len(b.max))
+	loudest := 0.0
+	for i := range values {
+		if useMean {
+			if b.count[i] > 0 {
+				values[i] = b.sum[i] / float64(b.count[i])
+			}
+		} else {
+			values[i] = b.max[i]
+		}
+		if b.count[i] > 0 {
+			values[i] = math.Max(values[i]-floor, 0)
+		}
+		loudest = math.Max(loudest, values[i])
+	}
+
+	peaks := make([]int, len(values))
+	if loudest == 0 {
+		return peaks
+	}
+	for i, value := range values {
+		peaks[i] = int(math.Round(value / loudest * 100))
+	}
+	return peaks
+}
+
+// probeWAV reads the fmt and data chunks of a RIFF WAVE file holding integer
+// or float PCM, and takes peaks from the first channel's samples
+func probeWAV(data []byte, peakCount int) (AudioInfo, error) {
+	info := AudioInfo{Format: AudioFormatWAV, ContentType: "audio/wav"}
+
+	var format, channels, blockAlign, bits uint16
+	var sampleRate, byteRate uint32
+	var samples []byte
+	for pos := 12; pos+8 <= len(data); {
+		id, size := string(data[pos:pos+4]), int(binary.LittleEndian.Uint32(data[pos+4:pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			if id != "data" {
+				return info, ErrCorruptAudio
+			}
+			size = len(body) // Streams written without a final size
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return info, ErrCorruptAudio
+			}
+			format = binary.LittleEndian.Uint16(body[0:2])
+			channels = binary.LittleEndian.Uint16(body[2:4])
+			sampleRate = binary.LittleEndian.Uint32(body[4:8])
+			byteRate = binary.LittleEndian.Uint32(body[8:12])
+			blockAlign = binary.LittleEndian.Uint16(body[12:14])
+			bits = binary.LittleEndian.Uint16(body[14:16])
+			if format == 0xFFFE && size >= 26 {
+				format = binary.LittleEndian.Uint16(body[24:26]) // WAVE_FORMAT_EXTENSIBLE sub-format
+			}
+		case "data":
+			samples = body
+		}
+		pos += 8 + size + size%2
+	}
+
+	if sampleRate == 0 || byteRate == 0 || blockAlign == 0 || channels == 0 || samples == nil {
+		return info, ErrCorruptAudio
+	}
+	if !(format == 1 && (bits == 8 || bits == 16 || bits == 24 || bits == 32)) && !(format == 3 && bits == 32) {
+		return info, ErrUnsupportedAudio
+	}
+	// Each frame must hold a sample of the declared width for every channel
+	width := int(bits / 8)
+	if width < 1 || int(blockAlign) < int(channels)*width {
+		return info, ErrCorruptAudio
+	}
+	// The byte rate is redundant; a mismatch would let it misreport the duration
+	if uint64(byteRate) != uint64(sampleRate)*uint64(blockAlign) {
+		return info, ErrCorruptAudio
+	}
+
+	frames := len(samples) / int(blockAlign)
+	info.Duration = time.Duration(float64(frames) / float64(sampleRate) * float64(time.Second))
+	buckets := newPeakBuckets(peakCount)
+	for frame := 0; frame < frames; frame++ {
+		sample := samples[frame*int(blockAlign):]
+		if len(sample) < width {
+			break
+		}
+		var value float64
+		switch {
+		case format == 3:
+			value = float64(math.Float32frombits(binary.LittleEndian.Uint32(sample)))
+		case bits == 8:
+			value = (float64(sample[0]) - 128) / 128
+		case bits == 16:
+			value = float64(int16(binary.LittleEndian.Uint16(sample))) / 32768
+		case bits == 24:
+			value = float64(int32(uint32(sample[0])<<8|uint32(sample[1])<<16|uint32(sample[2])<<24)>>8) / 8388608
+		default:
+			value = float64(int32(binary.LittleEndian.Uint32(sample))) / 2147483648
+		}
+		buckets.add(float64(frame)/float64(frames), math.Abs(value))
+	}
+	info.Peaks = buckets.peaks(false, 0)
+	return info, nil
+}
+
+// bitReader reads big-endian bit fields, as used in MPEG audio side information
+type bitReader struct {
+	data []byte
+	pos  int // In bits
+}
+
+func (r *bitReader) read(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		byteIndex := r.pos / 8
+		bit := 0
+		if byteIndex < len(r.data) {
+			bit = int(r.data[byteIndex]>>(7-uint(r.pos%8))) & 1
+		}
+		value = value<<1 | bit
+		r.pos++
+	}
+	return value
+}
+
+// hasPrefix reports whether data at offset starts with prefix
+func hasPrefix(data []byte, offset int, prefix string) bool {
+	return offset >= 0 && offset+len(prefix) <= len(data) && bytes.Equal(data[offset:offset+len(prefix)], []byte(prefix))
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// mp4Box is one box (atom) of an MP4 file
+type mp4Box struct {
+	kind string
+	body []byte
+}
+
+// mp4Boxes splits data into its top-level boxes
+func mp4Boxes(data []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for pos := 0; pos+8 <= len(data); {
+		size := int64(binary.BigEndian.Uint32(data[pos : pos+4]))
+		kind := string(data[pos+4 : pos+8])
+		header := int64(8)
+		switch size {
+		case 0:
+			size = int64(len(data) - pos) // Runs to the end of the file
+		case 1:
+			if pos+16 > len(data) {
+				return nil, ErrCorruptAudio
+			}
+			size, header = int64(binary.BigEndian.Uint64(data[pos+8:pos+16])), 16
+		}
+		if size < header || int64(pos)+size > int64(len(data)) {
+			return nil, ErrCorruptAudio
+		}
+		boxes = append(boxes, mp4Box{kind: kind, body: data[int64(pos)+header : int64(pos)+size]})
+		pos += int(size)
+	}
+	return boxes, nil
+}
+
+// mp4Child returns the first child box of the given kind
+func mp4Child(body []byte, kind string) ([]byte, bool) {
+	boxes, err := mp4Boxes(body)
+	if err != nil {
+		return nil, false
+	}
+	for _, box := range boxes {
+		if box.kind == kind {
+			return box.body, true
+		}
+	}
+	return nil, false
+}
+
+// mp4Path follows a path of nested boxes, such as mdia, minf, stbl
+func mp4Path(body []byte, kinds ...string) ([]byte, bool) {
+	for _, kind := range kinds {
+		var ok bool
+		if body, ok = mp4Child(body, kind); !ok {
+			return nil, false
+		}
+	}
+	return body, true
+}
+
+// probeM4A finds the sound track of an MP4 audio file (M4A, usually AAC).
+// The duration is the total of the sample-to-time table, which is what
+// players follow, in the timescale of the track's media header; peaks follow
+// the size of each sample, timed with the same table.
+func probeM4A(data []byte, peakCount int) (AudioInfo, error) {
+	info := AudioInfo{Format: AudioFormatM4A, ContentType: "audio/mp4"}
+
+	moov, ok := mp4Child(data, "moov")
+	if !ok {
+		return info, ErrCorruptAudio
+	}
+	traks, err := mp4Boxes(moov)
+	if err != nil {
+		return info, err
+	}
+
+	for _, trak := range traks {
+		if trak.kind != "trak" {
+			continue
+		}
+		hdlr, ok := mp4Path(trak.body, "mdia", "hdlr")
+		if !ok || len(hdlr) < 12 {
+			continue
+		}
+		switch string(hdlr[8:12]) {
+		case "vide":
+			return info, ErrUnsupportedAudio // Video files are not voice comments
+		case "soun":
+		default:
+			continue
+		}
+
+		// Timescale, and the duration to fall back on, from the media header
+		mdhd, ok := mp4Path(trak.body, "mdia", "mdhd")
+		if !ok || len(mdhd) < 24 {
+			return info, ErrCorruptAudio
+		}
+		var timescale, duration uint64
+		if mdhd[0] == 1 {
+			if len(mdhd) < 36 {
+				return info, ErrCorruptAudio
+			}
+			timescale, duration = uint64(binary.BigEndian.Uint32(mdhd[20:24])), binary.BigEndian.Uint64(mdhd[24:32])
+		} else {
+			timescale, duration = uint64(binary.BigEndian.Uint32(mdhd[12:16])), uint64(binary.BigEndian.Uint32(mdhd[16:20]))
+		}
+		if timescale == 0 || duration == 0 {
+			return info, ErrCorruptAudio
+		}
+
+		stbl, ok := mp4Path(trak.body, "mdia", "minf", "stbl")
+		if !ok {
+			return info, ErrCorruptAudio
+		}
+		if total := m4aTableDuration(stbl); total > 0 {
+			duration = total
+		}
+		info.Duration = time.Duration(math.MaxInt64) // Far past any limit, however the float rounds
+		if seconds := float64(duration) / float64(timescale); seconds < float64(math.MaxInt64/int64(time.Second)) {
+			info.Duration = time.Duration(seconds * float64(time.Second))
+		}
+		info.Peaks = m4aPeaks(stbl, duration, peakCount)
+		return info, nil
+	}
+	return info, ErrUnsupportedAudio
+}
+
+// m4aTableDuration adds up the sample-to-time table in timescale ticks, or
+// returns 0 when there is none. Only the entries the table holds count, and
+// the total saturates rather than wrapping around.
+func m4aTableDuration(stbl []byte) uint64 {
+	stts, ok := mp4Child(stbl, "stts")
+	if !ok || len(stts) < 8 {
+		return 0
+	}
+	entries := int(binary.BigEndian.Uint32(stts[4:8]))
+	var total uint64
+	for e := 0; e < entries && 8+e*8+8 <= len(stts); e++ {
+		ticks := uint64(binary.BigEndian.Uint32(stts[8+e*8:])) * uint64(binary.BigEndian.Uint32(stts[12+e*8:]))
+		if total+ticks < total {
+			return math.MaxUint64
+		}
+		total += ticks
+	}
+	return total
+}
+
+// m4aPeaks buckets sample sizes from stsz by their start time from stts.
+// Files without these tables get a flat waveform. The work is bounded by the
+// tables' length and peakCount, never by the counts the file claims.
+func m4aPeaks(stbl []byte, duration uint64, peakCount int) []int {
+	buckets := newPeakBuckets(peakCount)
+	stsz, okSizes := mp4Child(stbl, "stsz")
+	stts, okTimes := mp4Child(stbl, "stts")
+	if !okSizes || !okTimes || len(stsz) < 12 || len(stts) < 8 {
+		return buckets.peaks(true, 0)
+	}
+
+	fixedSize := binary.BigEndian.Uint32(stsz[4:8])
+	count := int(binary.BigEndian.Uint32(stsz[8:12]))
+	if fixedSize == 0 {
+		// Each sample has its own size entry, so there are no more than the table holds
+		count = min(count, (len(stsz)-12)/4)
+	}
+	sampleSize := func(i int) float64 {
+		if fixedSize != 0 {
+			return float64(fixedSize)
+		}
+		offset := 12 + i*4
+		return float64(binary.BigEndian.Uint32(stsz[offset : offset+4]))
+	}
+
+	// Samples of a fixed size all weigh the same, so one per bucket is enough:
+	// runs of them skip ahead a bucket at a time and stop past the end
+	bucketTicks := duration / uint64(max(peakCount, 1))
+
+	entries := int(binary.BigEndian.Uint32(stts[4:8]))
+	sample := 0
+	var elapsed uint64
+	for e := 0; e < entries && 8+e*8+8 <= len(stts) && sample < count; e++ {
+		samples := int(binary.BigEndian.Uint32(stts[8+e*8:]))
+		delta := uint64(binary.BigEndian.Uint32(stts[12+e*8:]))
+		stride := 1
+		if fixedSize != 0 {
+			switch {
+			case delta == 0:
+				stride = max(samples, 1)
+			case bucketTicks/delta > 1:
+				stride = int(min(bucketTicks/delta, uint64(max(samples, 1))))
+			}
+		}
+		for i := 0; i < samples && sample < count; i += stride {
+			if fixedSize != 0 && elapsed > duration {
+				return buckets.peaks(true, 0)
+			}
+			buckets.add(float64(elapsed)/float64(duration), sampleSize(sample))
+			step := min(stride, samples-i, count-sample)
+			elapsed += delta * uint64(step)
+			sample += step
+		}
+	}
+	return buckets.peaks(true, 0)
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"time"
+)
+
+// MPEG audio layer III bitrates in kbit/s by bitrate index
+var (
+	mp3BitratesV1 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3Rates      = [3]int{44100, 48000, 32000}
+)
+
+// mp3Frame is one parsed MPEG audio layer III frame header
+type mp3Frame struct {
+	mpeg1      bool
+	mono       bool
+	crc        bool
+	length     int
+	samples    int
+	sampleRate int
+}
+
+// parseMP3Frame reads the frame header at data[pos:], reporting false for
+// anything that is not a valid layer III header
+func parseMP3Frame(data []byte, pos int) (mp3Frame, bool) {
+	if pos+4 > len(data) || data[pos] != 0xFF || data[pos+1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := (data[pos+1] >> 3) & 3 // 0: MPEG 2.5, 2: MPEG 2, 3: MPEG 1
+	layer := (data[pos+1] >> 1) & 3   // 1: layer III
+	bitrateIndex := data[pos+2] >> 4
+	rateIndex := (data[pos+2] >> 2) & 3
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	frame := mp3Frame{
+		mpeg1: version == 3,
+		mono:  data[pos+3]>>6 == 3,
+		crc:   data[pos+1]&1 == 0,
+	}
+	padding := int(data[pos+2]>>1) & 1
+	frame.sampleRate = mp3Rates[rateIndex]
+	if frame.mpeg1 {
+		frame.samples = 1152
+		frame.length = 144*mp3BitratesV1[bitrateIndex]*1000/frame.sampleRate + padding
+	} else {
+		frame.sampleRate /= 2
+		if version == 0 {
+			frame.sampleRate /= 2
+		}
+		frame.samples = 576
+		frame.length = 72*mp3BitratesV2[bitrateIndex]*1000/frame.sampleRate + padding
+	}
+	return frame, frame.length > 4
+}
+
+// globalGain returns the largest global_gain in the frame's side information,
+// the quantizer step size that sets the frame's overall loudness
+func (frame mp3Frame) globalGain(data []byte) int {
+	start := 4
+	if frame.crc {
+		start += 2
+	}
+	if start >= len(data) {
+		return 0
+	}
+	r := &bitReader{data: data[start:]}
+
+	channels, granules := 2, 2
+	if frame.mono {
+		channels = 1
+	}
+	if frame.mpeg1 {
+		r.read(9) // main_data_begin
+		if frame.mono {
+			r.read(5)
+		} else {
+			r.read(3)
+		}
+		r.read(4 * channels) // scfsi
+	} else {
+		granules = 1
+		r.read(8)
+		r.read(channels)
+	}
+
+	gain := 0
+	for gr := 0; gr < granules; gr++ {
+		for ch := 0; ch < channels; ch++ {
+			r.read(12 + 9) // part2_3_length, big_values
+			if g := r.read(8); g > gain {
+				gain = g
+			}
+			if frame.mpeg1 {
+				r.read(4 + 1 + 22 + 3) // scalefac_compress, window switching and tables, flags
+			} else {
+				r.read(9 + 1 + 22 + 2)
+			}
+		}
+	}
+	return gain
+}
+
+// probeMP3 walks every frame to measure the exact duration, variable bitrate
+// included, recording each frame's global gain for the waveform
+func probeMP3(data []byte, peakCount int) (AudioInfo, error) {
+	info := AudioInfo{Format: AudioFormatMP3, ContentType: "audio/mpeg"}
+
+	// Skip an ID3v2 tag
+	pos := 0
+	if hasPrefix(data, 0, "ID3") && len(data) >= 10 {
+		size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9])
+		pos = 10 + size
+		if data[5]&0x10 != 0 {
+			pos += 10 // Footer
+		}
+	}
+
+	type frameGain struct {
+		start time.Duration
+		gain  int
+	}
+	var gains []frameGain
+	var elapsed time.Duration
+	minGain := 255
+	for pos < len(data) {
+		frame, ok := parseMP3Frame(data, pos)
+		// A header only counts if the next frame, or the end of the file, follows it
+		if ok && pos+frame.length < len(data) {
+			if _, next := parseMP3Frame(data, pos+frame.length); !next && !hasPrefix(data, pos+frame.length, "TAG") {
+				ok = false
+			}
+		}
+		if !ok {
+			if len(gains) == 0 && pos > 64*1024 {
+				break // No audio near the start; not an MP3
+			}
+			pos++
+			continue
+		}
+
+		end := pos + frame.length
+		if end > len(data) {
+			end = len(data)
+		}
+		gain := frame.globalGain(data[pos:end])
+		gains = append(gains, frameGain{start: elapsed, gain: gain})
+		if gain < minGain {
+			minGain = gain
+		}
+		elapsed += time.Duration(frame.samples) * time.Second / time.Duration(frame.sampleRate)
+		pos += frame.length
+	}
+	if len(gains) < 2 {
+		return info, ErrUnsupportedAudio
+	}
+	info.Duration = elapsed
+
+	buckets := newPeakBuckets(peakCount)
+	for _, g := range gains {
+		buckets.add(float64(g.start)/float64(elapsed), float64(g.gain))
+	}
+	info.Peaks = buckets.peaks(false, float64(minGain))
+	return info, nil
+}
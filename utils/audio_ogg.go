@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// probeOGG reads the first logical stream of an Ogg file holding Opus or
+// Vorbis. The duration comes from the last page's granule position; peaks
+// follow the size of the audio packets on each page.
+func probeOGG(data []byte, peakCount int) (AudioInfo, error) {
+	info := AudioInfo{Format: AudioFormatOGG, ContentType: "audio/ogg"}
+
+	type page struct {
+		granule int64
+		bytes   int // Audio packet bytes on the page
+		packets int
+	}
+	var pages []page
+	var serial uint32
+	var packet []byte
+	packets := 0
+	headerPackets := 0
+	sampleRate := 0
+	var preSkip int64
+
+	for pos := 0; pos+27 <= len(data); {
+		if !hasPrefix(data, pos, "OggS") {
+			return info, ErrCorruptAudio
+		}
+		granule := int64(binary.LittleEndian.Uint64(data[pos+6 : pos+14]))
+		pageSerial := binary.LittleEndian.Uint32(data[pos+14 : pos+18])
+		segments := int(data[pos+26])
+		if pos+27+segments > len(data) {
+			return info, ErrCorruptAudio
+		}
+		lacing := data[pos+27 : pos+27+segments]
+		body := pos + 27 + segments
+		if packets == 0 && len(pages) == 0 {
+			serial = pageSerial
+		}
+
+		current := page{granule: granule}
+		offset := body
+		for _, size := range lacing {
+			end := offset + int(size)
+			if end > len(data) {
+				return info, ErrCorruptAudio
+			}
+			if pageSerial == serial {
+				packet = append(packet, data[offset:end]...)
+			}
+			offset = end
+			if size == 255 || pageSerial != serial {
+				continue
+			}
+
+			// A complete packet: the first ones are codec headers
+			switch {
+			case packets == 0 && hasPrefix(packet, 0, "OpusHead") && len(packet) >= 12:
+				sampleRate, headerPackets = 48000, 2
+				preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+			case packets == 0 && hasPrefix(packet, 0, "\x01vorbis") && len(packet) >= 16:
+				sampleRate, headerPackets = int(binary.LittleEndian.Uint32(packet[12:16])), 3
+			case packets == 0:
+				return info, ErrUnsupportedAudio
+			case packets >= headerPackets:
+				current.bytes += len(packet)
+				current.packets++
+			}
+			packets++
+			packet = packet[:0]
+		}
+		if pageSerial == serial && current.packets > 0 && granule >= 0 {
+			pages = append(pages, current)
+		}
+		pos = offset
+	}
+
+	if sampleRate == 0 || len(pages) == 0 {
+		return info, ErrCorruptAudio
+	}
+	total := pages[len(pages)-1].granule - preSkip
+	if total <= 0 {
+		return info, ErrCorruptAudio
+	}
+	info.Duration = time.Duration(total) * time.Second / time.Duration(sampleRate)
+
+	buckets := newPeakBuckets(peakCount)
+	for _, p := range pages {
+		buckets.add(float64(p.granule-preSkip)/float64(total), float64(p.bytes)/float64(p.packets))
+	}
+	info.Peaks = buckets.peaks(true, 0)
+	return info, nil
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// testWAV builds a WAVE file from a fmt chunk and a data chunk, always at 8 kHz
+func testWAV(format, channels uint16, byteRate uint32, blockAlign, bits uint16, samples []byte) []byte {
+	var fmtChunk bytes.Buffer
+	binary.Write(&fmtChunk, binary.LittleEndian, format)
+	binary.Write(&fmtChunk, binary.LittleEndian, channels)
+	binary.Write(&fmtChunk, binary.LittleEndian, uint32(8000))
+	binary.Write(&fmtChunk, binary.LittleEndian, byteRate)
+	binary.Write(&fmtChunk, binary.LittleEndian, blockAlign)
+	binary.Write(&fmtChunk, binary.LittleEndian, bits)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(4+8+fmtChunk.Len()+8+len(samples)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(fmtChunk.Len()))
+	b.Write(fmtChunk.Bytes())
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(len(samples)))
+	b.Write(samples)
+	return b.Bytes()
+}
+
+func TestProbeWAVMalformedHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"float samples wider than the block", testWAV(3, 1, 4, 1, 32, []byte{1, 2, 3}), ErrCorruptAudio},
+		{"16-bit samples wider than the block", testWAV(1, 1, 2, 1, 16, []byte{1, 2, 3}), ErrCorruptAudio},
+		{"block too small for every channel", testWAV(1, 2, 4, 2, 16, []byte{1, 2, 3, 4}), ErrCorruptAudio},
+		{"zero block align", testWAV(1, 1, 2, 0, 16, []byte{1, 2}), ErrCorruptAudio},
+		{"zero channels", testWAV(1, 0, 2, 2, 16, []byte{1, 2}), ErrCorruptAudio},
+		{"zero byte rate", testWAV(1, 1, 0, 2, 16, []byte{1, 2}), ErrCorruptAudio},
+		{"byte rate overstating the sample rate", testWAV(1, 1, 1000000000, 2, 16, make([]byte, 16000)), ErrCorruptAudio},
+		{"byte rate understating the sample rate", testWAV(1, 1, 8000, 2, 16, make([]byte, 16000)), ErrCorruptAudio},
+		{"unsupported sample width", testWAV(1, 1, 1, 1, 4, []byte{1, 2}), ErrUnsupportedAudio},
+		{"unsupported format", testWAV(2, 1, 2, 2, 16, []byte{1, 2}), ErrUnsupportedAudio},
+		{"fmt chunk too short", []byte("RIFF\x10\x00\x00\x00WAVEfmt \x04\x00\x00\x00\x01\x00\x01\x00"), ErrCorruptAudio},
+		{"no data chunk", testWAV(1, 1, 2, 2, 16, nil)[:36], ErrCorruptAudio},
+		{"chunk larger than the file", []byte("RIFF\x10\x00\x00\x00WAVEfmt \xFF\x00\x00\x00"), ErrCorruptAudio},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ProbeAudio(tt.data, 8); !errors.Is(err, tt.want) {
+				t.Fatalf("ProbeAudio() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeWAVValid(t *testing.T) {
+	samples := make([]byte, 16000) // One second of 16-bit mono at 8 kHz
+	for i := 0; i < len(samples); i += 2 {
+		binary.LittleEndian.PutUint16(samples[i:], uint16(int16(i)))
+	}
+	data := testWAV(1, 1, 16000, 2, 16, samples)
+
+	info, err := ProbeAudio(data, 8)
+	if err != nil {
+		t.Fatalf("ProbeAudio() error = %v", err)
+	}
+	if info.Format != AudioFormatWAV || info.Duration.Seconds() != 1 || len(info.Peaks) != 8 {
+		t.Fatalf("ProbeAudio() = %+v", info)
+	}
+
+	// Truncating the file anywhere must never panic
+	for n := 0; n < 64; n++ {
+		ProbeAudio(data[:n], 8)
+	}
+	ProbeAudio(data[:len(data)-1], 8)
+}
+
+// testBox builds an MP4 box from its kind and body
+func testBox(kind string, body ...[]byte) []byte {
+	joined := bytes.Join(body, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(joined)))
+	return append(append(box, kind...), joined...)
+}
+
+// testM4A builds an M4A file with one sound track of the given length in
+// 1/1000 s ticks, its sample tables taken as they are
+func testM4A(duration uint32, stsz, stts []byte) []byte {
+	hdlr := append(make([]byte, 8), "soun"...)
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:], 1000)
+	binary.BigEndian.PutUint32(mdhd[16:], duration)
+	stbl := testBox("stbl", testBox("stsz", stsz), testBox("stts", stts))
+	mdia := testBox("mdia", testBox("hdlr", hdlr), testBox("mdhd", mdhd), testBox("minf", stbl))
+	return append(testBox("ftyp", []byte("M4A \x00\x00\x00\x00")), testBox("moov", testBox("trak", mdia))...)
+}
+
+// testTable builds a full box body of 32-bit fields
+func testTable(fields ...uint32) []byte {
+	table := make([]byte, 4) // Version and flags
+	for _, field := range fields {
+		table = binary.BigEndian.AppendUint32(table, field)
+	}
+	return table
+}
+
+func TestProbeM4AHugeSampleCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		stsz, stts []byte
+	}{
+		{"fixed size, one tick each", testTable(100, 4_000_000_000), testTable(1, 4_000_000_000, 1)},
+		{"fixed size, no time between samples", testTable(100, 4_000_000_000), testTable(1, 4_000_000_000, 0)},
+		{"fixed size, past the end at once", testTable(100, 4_000_000_000), testTable(1, 4_000_000_000, 4_000_000_000)},
+		{"sizes missing from the table", testTable(0, 4_000_000_000, 5, 6), testTable(1, 4_000_000_000, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			if _, err := ProbeAudio(testM4A(10_000, tt.stsz, tt.stts), 8); err != nil {
+				t.Fatalf("ProbeAudio() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Fatalf("ProbeAudio() took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestProbeM4APeaks(t *testing.T) {
+	// Ten seconds of fixed-size samples fill every bucket evenly
+	info, err := ProbeAudio(testM4A(10_000, testTable(100, 1000), testTable(1, 1000, 10)), 8)
+	if err != nil {
+		t.Fatalf("ProbeAudio() error = %v", err)
+	}
+	for i, peak := range info.Peaks {
+		if peak != 100 {
+			t.Fatalf("Peaks[%d] = %d, want 100: %v", i, peak, info.Peaks)
+		}
+	}
+
+	// Variable sizes land in the bucket of their start time
+	info, err = ProbeAudio(testM4A(10_000, testTable(0, 4, 100, 50, 50, 25), testTable(1, 4, 2500)), 4)
+	if err != nil {
+		t.Fatalf("ProbeAudio() error = %v", err)
+	}
+	if want := []int{100, 50, 50, 25}; fmt.Sprint(info.Peaks) != fmt.Sprint(want) {
+		t.Fatalf("Peaks = %v, want %v", info.Peaks, want)
+	}
+}
+
+func TestProbeM4ADurationFromSampleTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   uint32
+		stts     []byte
+		wantSecs float64
+	}{
+		{"header understating the table", 10, testTable(2, 1000, 50, 100, 100), 60},
+		{"header overstating the table", 600_000, testTable(1, 1000, 10), 10},
+		{"table overflowing", 10, testTable(2, 4_000_000_000, 4_000_000_000, 4_000_000_000, 4_000_000_000), math.MaxInt64 / 1e9},
+		{"no entries, header kept", 2500, testTable(0), 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ProbeAudio(testM4A(tt.header, testTable(100, 1000), tt.stts), 8)
+			if err != nil {
+				t.Fatalf("ProbeAudio() error = %v", err)
+			}
+			if got := info.Duration.Seconds(); math.Abs(got-tt.wantSecs) > 1e-6 {
+				t.Fatalf("Duration = %vs, want %vs", got, tt.wantSecs)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// OffensiveWords is the built-in filter list, matched alongside users' own
+// phrases unless they opt out. It is on for every account, so it only holds
+// phrases that are abusive in any context; words that are often harmless,
+// like "dumb" or "trash", are left for users to add to their own filters.
+var OffensiveWords = []string{
+	"kill yourself", "kill urself", "kys", "go die", "hope you die",
+	"nobody likes you", "no one likes you", "everyone hates you",
+	"you should die", "you are worthless", "youre worthless", "you re worthless",
+}
+
+// filterWords splits text into lowercase words of letters and digits
+func filterWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// NormalizeFilterPhrase lowercases a filter phrase and collapses punctuation
+// and spacing, so "Shut  UP!" and "shut up" are the same phrase. It returns
+// "" when nothing filterable is left.
+func NormalizeFilterPhrase(phrase string) string {
+	return strings.Join(filterWords(phrase), " ")
+}
+
+// MatchFilter returns the first phrase found in text as whole words, ignoring
+// case and punctuation, so "die" matches "just die." but not "diet". Phrases
+// must already be normalized.
+func MatchFilter(text string, phrases []string) (string, bool) {
+	padded := " " + strings.Join(filterWords(text), " ") + " "
+	for _, phrase := range phrases {
+		if phrase != "" && strings.Contains(padded, " "+phrase+" ") {
+			return phrase, true
+		}
+	}
+	return "", false
+}
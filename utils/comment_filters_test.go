@@ -0,0 +1,52 @@
+package utils
+
+import "testing"
+
+func TestMatchFilter(t *testing.T) {
+	phrases := []string{"die", "shut up", NormalizeFilterPhrase("Go  AWAY!")}
+	tests := []struct {
+		text   string
+		want   string
+		wanted bool
+	}{
+		{"just die.", "die", true},
+		{"DIE", "die", true},
+		{"(die)", "die", true},
+		{"on a diet", "", false},
+		{"indie music", "", false},
+		{"died yesterday", "", false},
+		{"Shut   UP!!", "shut up", true},
+		{"shut-up", "shut up", true},
+		{"shut the door, up there", "", false},
+		{"shutup", "", false},
+		{"please go away now", "go away", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, matched := MatchFilter(tt.text, phrases)
+		if got != tt.want || matched != tt.wanted {
+			t.Errorf("MatchFilter(%q) = %q, %v, want %q, %v", tt.text, got, matched, tt.want, tt.wanted)
+		}
+	}
+
+	if _, matched := MatchFilter("anything", []string{""}); matched {
+		t.Error("MatchFilter() matched an empty phrase")
+	}
+	if got, _ := MatchFilter("please kill yourself", OffensiveWords); got != "kill yourself" {
+		t.Errorf("MatchFilter() with OffensiveWords = %q, want %q", got, "kill yourself")
+	}
+}
+
+func TestNormalizeFilterPhrase(t *testing.T) {
+	tests := map[string]string{
+		"Shut  UP!":   "shut up",
+		"  die  ":     "die",
+		"you're dumb": "you re dumb",
+		"!!!":         "",
+	}
+	for phrase, want := range tests {
+		if got := NormalizeFilterPhrase(phrase); got != want {
+			t.Errorf("NormalizeFilterPhrase(%q) = %q, want %q", phrase, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxHashtagLength is the longest hashtag that is indexed; longer tags are ignored
+const MaxHashtagLength = 100
+
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/])#([\p{L}\p{N}_]+)`)
+
+var digitsOnly = regexp.MustCompile(`^[0-9]+$`)
+
+// ExtractHashtags returns the distinct, lowercased hashtags in text, without
+// the leading "#", in order of first appearance. Numeric-only tags such as
+// "#1" are not hashtags.
+func ExtractHashtags(texts ...string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+			tag := NormalizeHashtag(match[1])
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizeHashtag lowercases a tag and strips a leading "#", returning ""
+// when the result is not a valid hashtag
+func NormalizeHashtag(tag string) string {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" || len(tag) > MaxHashtagLength || digitsOnly.MatchString(tag) {
+		return ""
+	}
+	for _, r := range tag {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return ""
+		}
+	}
+	return tag
+}
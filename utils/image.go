@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder
+)
+
+// Image rendition names
+const (
+	RenditionThumb = "thumb" // Square crop for grids
+	RenditionFeed  = "feed"  // Sized for feeds
+	RenditionFull  = "full"  // Largest size served
+)
+
+// ImageRenditionSizes is the longest side, in pixels, of each rendition.
+// Images are never scaled up.
+var ImageRenditionSizes = map[string]int{
+	RenditionThumb: 320,
+	RenditionFeed:  1080,
+	RenditionFull:  2048,
+}
+
+// Image limits and output settings
+const (
+	// MaxImagePixels refuses larger images before decoding. Processing holds
+	// the decoded image and an RGBA copy at once, up to 12 bytes a pixel for
+	// 16-bit PNGs, so this keeps an upload under 300MB; 24 megapixels is what
+	// current phone cameras save by default.
+	MaxImagePixels   = 24_000_000
+	ImageJPEGQuality = 82
+)
+
+// ErrUnsupportedImage is returned for files that are not JPEG, PNG or GIF images
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// ErrCorruptImage is returned for images that cannot be decoded
+var ErrCorruptImage = errors.New("image is corrupt or truncated")
+
+// ErrImageTooLarge is returned for images with more than MaxImagePixels pixels
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+// ImageRendition is one resized copy of an image, encoded as JPEG
+type ImageRendition struct {
+	Name   string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// ProcessedImage is an uploaded image made ready to serve
+type ProcessedImage struct {
+	Width      int // Of the upright original
+	Height     int
+	Renditions []ImageRendition
+}
+
+// ProcessImage decodes a JPEG, PNG or GIF upload, turns it upright according
+// to its EXIF orientation and encodes the renditions in ImageRenditionSizes.
+// Re-encoding drops all metadata, EXIF and GPS included. Renditions are JPEG
+// because the standard library has no WebP encoder; transparent areas are
+// filled with white and only the first frame of an animated GIF is kept.
+func ProcessImage(data []byte) (ProcessedImage, error) {
+	var processed ProcessedImage
+
+	// Check the format and size from the header before decoding the pixels
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processed, ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return processed, ErrCorruptImage
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return processed, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processed, ErrCorruptImage
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	// Only the RGBA copy is used from here on, so the collector can free the
+	// decoded image; turning it upright in place avoids a third full copy
+	upright := flatten(decoded)
+	orient(upright, orientation)
+	processed.Width, processed.Height = upright.Bounds().Dx(), upright.Bounds().Dy()
+
+	// Each rendition is scaled from the next larger one, which is quicker than
+	// starting from the original every time and looks the same
+	source := upright
+	for _, name := range []string{RenditionFull, RenditionFeed, RenditionThumb} {
+		maxSide := ImageRenditionSizes[name]
+		var resized *image.RGBA
+		if name == RenditionThumb {
+			square := centerSquare(source)
+			side := min(maxSide, square.Bounds().Dx())
+			resized = resizeArea(square, side, side)
+		} else {
+			width, height := fitWithin(source.Bounds().Dx(), source.Bounds().Dy(), maxSide)
+			resized = resizeArea(source, width, height)
+			source = resized
+		}
+
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, resized, &jpeg.Options{Quality: ImageJPEGQuality}); err != nil {
+			return processed, err
+		}
+		processed.Renditions = append(processed.Renditions, ImageRendition{
+			Name:   name,
+			Data:   encoded.Bytes(),
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+		})
+	}
+	return processed, nil
+}
+
+// jpegOrientation reads the orientation tag (0x0112) from the EXIF block of
+// a JPEG, returning 1, upright, when there is none
+func jpegOrientation(data []byte) int {
+	if !hasPrefix(data, 0, "\xFF\xD8") {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break // Image data starts; metadata comes before it
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			break
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && hasPrefix(segment, 0, "Exif\x00\x00") {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 && order.Uint16(tiff[entry+2:entry+4]) == 3 {
+			if value := int(order.Uint16(tiff[entry+8 : entry+10])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// flatten copies img onto a white background, as JPEG has no transparency
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+	return flat
+}
+
+// orient turns img upright in place according to an EXIF orientation: 2 to
+// 4 mirror or turn it half way, 5 to 8 also swap its width and height
+func orient(img *image.RGBA, orientation int) {
+	if orientation < 2 || orientation > 8 {
+		return
+	}
+	if orientation >= 5 {
+		// Mirrored along the diagonal, then as 1 to 4
+		transpose(img)
+		orientation -= 4
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	switch orientation {
+	case 2: // Mirrored
+		for y := 0; y < h; y++ {
+			for x := 0; x < w/2; x++ {
+				swapPixels(img, x, y, w-1-x, y)
+			}
+		}
+	case 3: // Upside down
+		for i := 0; i < w*h/2; i++ {
+			swapPixels(img, i%w, i/w, w-1-i%w, h-1-i/w)
+		}
+	case 4: // Mirrored upside down
+		for y := 0; y < h/2; y++ {
+			for x := 0; x < w; x++ {
+				swapPixels(img, x, y, x, h-1-y)
+			}
+		}
+	}
+}
+
+// swapPixels exchanges two pixels of img
+func swapPixels(img *image.RGBA, x1, y1, x2, y2 int) {
+	a, b := img.Pix[img.PixOffset(x1, y1):img.PixOffset(x1, y1)+4], img.Pix[img.PixOffset(x2, y2):img.PixOffset(x2, y2)+4]
+	a[0], a[1], a[2], a[3], b[0], b[1], b[2], b[3] = b[0], b[1], b[2], b[3], a[0], a[1], a[2], a[3]
+}
+
+// transpose mirrors img along its diagonal in place, swapping its width and
+// height. The pixel at position i moves to i*h mod (w*h-1); each cycle of
+// that permutation is followed once, tracked in a bitset.
+func transpose(img *image.RGBA) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	n := w * h
+	if n > 2 {
+		moved := make([]uint64, (n+63)/64)
+		for start := 1; start < n-1; start++ {
+			if moved[start/64]&(1<<(start%64)) != 0 {
+				continue
+			}
+			var carried [4]byte
+			copy(carried[:], img.Pix[start*4:start*4+4])
+			for i := start; ; {
+				moved[i/64] |= 1 << (i % 64)
+				next := i * h % (n - 1)
+				var displaced [4]byte
+				copy(displaced[:], img.Pix[next*4:next*4+4])
+				copy(img.Pix[next*4:next*4+4], carried[:])
+				carried = displaced
+				if next == start {
+					break
+				}
+				i = next
+			}
+		}
+	}
+	img.Rect = image.Rect(0, 0, h, w)
+	img.Stride = h * 4
+}
+
+// centerSquare crops the middle square of img
+func centerSquare(img *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return img.SubImage(image.Rect(x, y, x+side, y+side)).(*image.RGBA)
+}
+
+// fitWithin scales width and height down so neither exceeds maxSide,
+// keeping the aspect ratio
+func fitWithin(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		return maxSide, max(1, (height*maxSide+width/2)/width)
+	}
+	return max(1, (width*maxSide+height/2)/height), maxSide
+}
+
+// areaWeight is a source pixel's share of an output pixel
+type areaWeight struct {
+	index  int
+	weight float32
+}
+
+// areaWeights lists, for each of the dst output pixels along one axis, the
+// source pixels it covers and how much of each, for scaling src pixels down
+func areaWeights(src, dst int) [][]areaWeight {
+	scale := float64(src) / float64(dst)
+	weights := make([][]areaWeight, dst)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for s := int(start); s < src && float64(s) < end; s++ {
+			covered := min(end, float64(s+1)) - max(start, float64(s))
+			if covered > 0 {
+				weights[i] = append(weights[i], areaWeight{index: s, weight: float32(covered / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+// resizeArea scales img down to width by height by averaging the source
+// pixels each output pixel covers, which keeps fine detail from aliasing.
+// Output rows are made one at a time, so only a row of sums is held beside
+// the two images.
+func resizeArea(img *image.RGBA, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	if sw == width && sh == height {
+		draw.Draw(out, out.Bounds(), img, bounds.Min, draw.Src)
+		return out
+	}
+
+	// Scale the source rows each output row covers across, then add them up
+	columns := areaWeights(sw, width)
+	sums := make([]float32, width*3)
+	for y, rowWeights := range areaWeights(sh, height) {
+		clear(sums)
+		for _, rw := range rowWeights {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+rw.index):]
+			for x, weights := range columns {
+				var r, g, b float32
+				for _, w := range weights {
+					r += float32(row[w.index*4]) * w.weight
+					g += float32(row[w.index*4+1]) * w.weight
+					b += float32(row[w.index*4+2]) * w.weight
+				}
+				sums[x*3] += r * rw.weight
+				sums[x*3+1] += g * rw.weight
+				sums[x*3+2] += b * rw.weight
+			}
+		}
+		for x := 0; x < width; x++ {
+			o := out.PixOffset(x, y)
+			out.Pix[o], out.Pix[o+1], out.Pix[o+2], out.Pix[o+3] = clampByte(sums[x*3]), clampByte(sums[x*3+1]), clampByte(sums[x*3+2]), 255
+		}
+	}
+	return out
+}
+
+func clampByte(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// testPixels builds a w by h image whose pixels each hold their own position
+func testPixels(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			copy(img.Pix[img.PixOffset(x, y):], []byte{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+func TestOrient(t *testing.T) {
+	// Where each upright pixel is read from in a stored w by h image, per EXIF orientation
+	tests := []struct {
+		orientation int
+		swapped     bool
+		source      func(x, y, w, h int) (int, int)
+	}{
+		{1, false, func(x, y, w, h int) (int, int) { return x, y }},
+		{2, false, func(x, y, w, h int) (int, int) { return w - 1 - x, y }},
+		{3, false, func(x, y, w, h int) (int, int) { return w - 1 - x, h - 1 - y }},
+		{4, false, func(x, y, w, h int) (int, int) { return x, h - 1 - y }},
+		{5, true, func(x, y, w, h int) (int, int) { return y, x }},
+		{6, true, func(x, y, w, h int) (int, int) { return y, h - 1 - x }},
+		{7, true, func(x, y, w, h int) (int, int) { return w - 1 - y, h - 1 - x }},
+		{8, true, func(x, y, w, h int) (int, int) { return w - 1 - y, x }},
+	}
+	sizes := [][2]int{{3, 2}, {5, 3}, {2, 7}, {1, 4}}
+	for _, tt := range tests {
+		for _, size := range sizes {
+			w, h := size[0], size[1]
+			img := testPixels(w, h)
+			orient(img, tt.orientation)
+
+			wantW, wantH := w, h
+			if tt.swapped {
+				wantW, wantH = h, w
+			}
+			if got := img.Bounds(); got.Dx() != wantW || got.Dy() != wantH {
+				t.Fatalf("orientation %d on %dx%d: bounds %v, want %dx%d", tt.orientation, w, h, got, wantW, wantH)
+			}
+			for y := 0; y < wantH; y++ {
+				for x := 0; x < wantW; x++ {
+					sx, sy := tt.source(x, y, w, h)
+					if got := img.RGBAAt(x, y); int(got.R) != sx || int(got.G) != sy {
+						t.Fatalf("orientation %d on %dx%d: pixel (%d,%d) came from (%d,%d), want (%d,%d)",
+							tt.orientation, w, h, x, y, got.R, got.G, sx, sy)
+					}
+				}
+			}
+		}
+	}
+}
+
+// testTIFF builds a TIFF block whose first IFD holds the given 12-byte entries
+func testTIFF(order binary.AppendByteOrder, entries ...[]byte) []byte {
+	tiff := []byte("II*\x00")
+	if order == binary.BigEndian {
+		tiff = []byte("MM\x00*")
+	}
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, uint16(len(entries)))
+	for _, entry := range entries {
+		tiff = append(tiff, entry...)
+	}
+	return tiff
+}
+
+// testEntry builds an IFD entry holding one SHORT value
+func testEntry(order binary.AppendByteOrder, tag, value uint16) []byte {
+	entry := order.AppendUint16(nil, tag)
+	entry = order.AppendUint16(entry, 3) // SHORT
+	entry = order.AppendUint32(entry, 1)
+	entry = order.AppendUint16(entry, value)
+	return append(entry, 0, 0)
+}
+
+func TestExifOrientation(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	tests := []struct {
+		name string
+		tiff []byte
+		want int
+	}{
+		{"little endian", testTIFF(le, testEntry(le, 0x0100, 640), testEntry(le, 0x0112, 6)), 6},
+		{"big endian", testTIFF(be, testEntry(be, 0x0112, 8)), 8},
+		{"byte order mismatch", testTIFF(be, testEntry(le, 0x0112, 6)), 1},
+		{"no orientation tag", testTIFF(le, testEntry(le, 0x0100, 640)), 1},
+		{"out of range value", testTIFF(le, testEntry(le, 0x0112, 9)), 1},
+		{"truncated IFD", testTIFF(le, testEntry(le, 0x0100, 640), testEntry(le, 0x0112, 3))[:8+2+12+6], 1},
+		{"IFD past the end", append([]byte("II*\x00"), 0xFF, 0xFF, 0, 0), 1},
+		{"unknown byte order", append([]byte("XX*\x00"), 8, 0, 0, 0, 0, 0), 1},
+		{"too short", []byte("II*\x00"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.tiff); got != tt.want {
+				t.Fatalf("exifOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// testPNGHeader builds a PNG that claims the given dimensions, with a valid
+// header checksum but no pixel data
+func testPNGHeader(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	// The IHDR chunk follows the 8-byte signature: length, type, then width and height
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestProcessImageTooLarge(t *testing.T) {
+	if _, err := ProcessImage(testPNGHeader(t, 6000, MaxImagePixels/6000+1)); err != ErrImageTooLarge {
+		t.Fatalf("ProcessImage() error = %v, want %v", err, ErrImageTooLarge)
+	}
+	if _, err := ProcessImage(testPNGHeader(t, 65535, 65535)); err != ErrImageTooLarge {
+		t.Fatalf("ProcessImage() error = %v, want %v", err, ErrImageTooLarge)
+	}
+}
+
+func TestProcessImageOrientsAndResizes(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, testPixels(40, 20)); err != nil {
+		t.Fatal(err)
+	}
+	processed, err := ProcessImage(b.Bytes())
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+	if processed.Width != 40 || processed.Height != 20 || len(processed.Renditions) != 3 {
+		t.Fatalf("ProcessImage() = %dx%d with %d renditions", processed.Width, processed.Height, len(processed.Renditions))
+	}
+	for _, rendition := range processed.Renditions {
+		wantW, wantH := 40, 20
+		if rendition.Name == RenditionThumb {
+			wantW = 20
+		}
+		if rendition.Width != wantW || rendition.Height != wantH {
+			t.Errorf("%s rendition is %dx%d, want %dx%d", rendition.Name, rendition.Width, rendition.Height, wantW, wantH)
+		}
+	}
+}
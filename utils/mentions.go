@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([\p{L}\p{N}_.]+)`)
+
+// MentionRange is an @username found in a piece of text. Start and End are
+// character (rune) offsets of the whole "@username", End exclusive.
+type MentionRange struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// ExtractMentions returns every @username in text in order of appearance.
+// Trailing dots are treated as punctuation rather than part of the username.
+func ExtractMentions(text string) []MentionRange {
+	mentions := []MentionRange{}
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		username := strings.TrimRight(text[match[2]:match[3]], ".")
+		if username == "" {
+			continue
+		}
+		start := utf8.RuneCountInString(text[:match[2]-1])
+		mentions = append(mentions, MentionRange{
+			Username: username,
+			Start:    start,
+			End:      start + 1 + utf8.RuneCountInString(username),
+		})
+	}
+	return mentions
+}
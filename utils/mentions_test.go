@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []MentionRange
+	}{
+		{"@alice", []MentionRange{{"alice", 0, 6}}},
+		{"@a and @b_c", []MentionRange{{"a", 0, 2}, {"b_c", 7, 11}}},
+		{"thanks @bob.", []MentionRange{{"bob", 7, 11}}},
+		{"(@ann) @jo.smith", []MentionRange{{"ann", 1, 5}, {"jo.smith", 7, 16}}},
+		{"café @zoë!", []MentionRange{{"zoë", 5, 9}}},
+		{"😀 @x", []MentionRange{{"x", 2, 4}}},
+		{"mail me at a@b.com", []MentionRange{}},
+		{"@@twice and @.", []MentionRange{}},
+		{"no mentions", []MentionRange{}},
+	}
+	for _, tt := range tests {
+		got := ExtractMentions(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractMentions(%q) = %+v, want %+v", tt.text, got, tt.want)
+			continue
+		}
+		// Clients slice the text by character, so each range must cover exactly "@username"
+		runes := []rune(tt.text)
+		for _, mention := range got {
+			if covered := string(runes[mention.Start:mention.End]); covered != "@"+mention.Username {
+				t.Errorf("ExtractMentions(%q): range %d-%d covers %q", tt.text, mention.Start, mention.End, covered)
+			}
+		}
+	}
+}
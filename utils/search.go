@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxSearchTerms caps how many words of a search query are used
+const MaxSearchTerms = 8
+
+var searchTermPattern = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// SearchTerms splits a search query into lowercase words, dropping
+// punctuation and operators so the terms are safe to build queries from
+func SearchTerms(query string) []string {
+	terms := searchTermPattern.FindAllString(strings.ToLower(query), MaxSearchTerms)
+	if terms == nil {
+		return []string{}
+	}
+	return terms
+}
+
+// LikeContains returns a LIKE pattern matching term anywhere, escaping the
+// LIKE wildcards with a backslash
+func LikeContains(term string) string {
+	return "%" + LikeEscape(term) + "%"
+}
+
+// LikePrefix returns a LIKE pattern matching values that start with term
+func LikePrefix(term string) string {
+	return LikeEscape(term) + "%"
+}
+
+// LikeEscape escapes the LIKE wildcards in term; queries must use ESCAPE '\'
+func LikeEscape(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Video rendition names
+const (
+	RenditionVideoSD = "480p"
+	RenditionVideoHD = "720p"
+)
+
+// VideoRenditionSizes is the shorter side, in pixels, of each video
+// rendition. Videos are never scaled up, so smaller sources get only the
+// first rendition, at their own size.
+var VideoRenditionSizes = map[string]int{
+	RenditionVideoSD: 480,
+	RenditionVideoHD: 720,
+}
+
+// VideoRenditionNames lists the video renditions from smallest to largest
+var VideoRenditionNames = []string{RenditionVideoSD, RenditionVideoHD}
+
+// Video types accepted for posts
+const (
+	VideoTypeMP4       = "video/mp4"
+	VideoTypeQuickTime = "video/quicktime"
+	VideoTypeWebM      = "video/webm"
+)
+
+// ErrInvalidVideo is returned for files ffprobe cannot read as a video
+var ErrInvalidVideo = errors.New("file is not a readable video")
+
+// VideoInfo describes an uploaded video
+type VideoInfo struct {
+	Duration time.Duration
+	Width    int // As displayed, after applying the rotation
+	Height   int
+}
+
+// ffmpegPath and ffprobePath locate the binaries, from FFMPEG_PATH and
+// FFPROBE_PATH or else the PATH
+func ffmpegPath() string  { return GetEnv("FFMPEG_PATH", "ffmpeg") }
+func ffprobePath() string { return GetEnv("FFPROBE_PATH", "ffprobe") }
+
+// DetectVideoType returns the video type of a file from its first bytes: MP4
+// and QuickTime by their ftyp box, WebM by its EBML header. It returns "" for
+// anything else, including M4A audio.
+func DetectVideoType(head []byte) string {
+	switch {
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		switch brand := string(head[8:12]); {
+		case brand == "qt  ":
+			return VideoTypeQuickTime
+		case strings.HasPrefix(brand, "M4A"), strings.HasPrefix(brand, "M4B"), strings.HasPrefix(brand, "M4P"):
+			return ""
+		}
+		return VideoTypeMP4
+	case len(head) >= 4 && string(head[0:4]) == "\x1A\x45\xDF\xA3":
+		return VideoTypeWebM
+	}
+	return ""
+}
+
+// runTool runs an ffmpeg binary and returns its output, adding the end of
+// what it logged to errors
+func runTool(ctx context.Context, path string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if detail := strings.TrimSpace(stderr.String()); detail != "" {
+		if len(detail) > 500 {
+			detail = detail[len(detail)-500:]
+		}
+		err = fmt.Errorf("%w: %s", err, detail)
+	}
+	return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+}
+
+// ProbeVideo reads the duration and display size of a video file with ffprobe
+func ProbeVideo(ctx context.Context, path string) (VideoInfo, error) {
+	var info VideoInfo
+	output, err := runTool(ctx, ffprobePath(), "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		// ffprobe ran to the end and could not read the file; a crash or kill is worth retrying
+		return info, fmt.Errorf("%w: %v", ErrInvalidVideo, err)
+	}
+	if err != nil {
+		return info, err
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecType string            `json:"codec_type"`
+			Width     int               `json:"width"`
+			Height    int               `json:"height"`
+			Tags      map[string]string `json:"tags"`
+			SideData  []struct {
+				Rotation float64 `json:"rotation"`
+			} `json:"side_data_list"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return info, fmt.Errorf("%w: unreadable ffprobe output", ErrInvalidVideo)
+	}
+
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "video" || info.Width != 0 {
+			continue // Only the first video stream is kept
+		}
+		// Phones record upright video sideways with a rotation, which ffmpeg applies
+		rotation, _ := strconv.ParseFloat(stream.Tags["rotate"], 64)
+		for _, side := range stream.SideData {
+			if side.Rotation != 0 {
+				rotation = side.Rotation
+			}
+		}
+		info.Width, info.Height = stream.Width, stream.Height
+		if quarter := int(math.Round(rotation/90)) % 2; quarter != 0 {
+			info.Width, info.Height = info.Height, info.Width
+		}
+	}
+	if info.Width <= 0 || info.Height <= 0 {
+		return info, fmt.Errorf("%w: no video stream", ErrInvalidVideo)
+	}
+
+	seconds, err := strconv.ParseFloat(probe.Format.Duration, 64)
+	if err != nil || seconds <= 0 {
+		return info, fmt.Errorf("%w: unknown duration", ErrInvalidVideo)
+	}
+	info.Duration = time.Duration(seconds * float64(time.Second))
+	return info, nil
+}
+
+// ExtractPosterFrame returns the upright frame at the given time as a JPEG
+func ExtractPosterFrame(ctx context.Context, path string, at time.Duration) ([]byte, error) {
+	frame, err := runTool(ctx, ffmpegPath(), "-v", "error", "-ss", strconv.FormatFloat(at.Seconds(), 'f', 3, 64),
+		"-i", path, "-frames:v", "1", "-map_metadata", "-1", "-f", "image2pipe", "-c:v", "mjpeg", "-q:v", "2", "pipe:1")
+	if err != nil {
+		return nil, err
+	}
+	if len(frame) == 0 {
+		return nil, fmt.Errorf("ffmpeg: no frame at %s", at)
+	}
+	return frame, nil
+}
+
+// VideoRenditionSize scales width and height so the shorter side is at most
+// shortSide, keeping the aspect ratio and rounding to the even sizes H.264
+// needs
+func VideoRenditionSize(width, height, shortSide int) (int, int) {
+	if short := min(width, height); short > shortSide {
+		width = int(math.Round(float64(width) * float64(shortSide) / float64(short)))
+		height = int(math.Round(float64(height) * float64(shortSide) / float64(short)))
+	}
+	return max(2, width&^1), max(2, height&^1)
+}
+
+// TranscodeVideo encodes a video as an H.264 and AAC MP4 of the given size,
+// upright and with the index at the front so playback can start while it
+// downloads. Metadata, location included, and extra streams are dropped.
+func TranscodeVideo(ctx context.Context, input, output string, width, height int) error {
+	_, err := runTool(ctx, ffmpegPath(), "-v", "error", "-y", "-i", input,
+		"-map", "0:v:0", "-map", "0:a:0?", "-map_metadata", "-1", "-map_chapters", "-1",
+		"-vf", fmt.Sprintf("scale=%d:%d,setsar=1", width, height),
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-profile:v", "high", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k", "-ac", "2",
+		"-movflags", "+faststart", output)
+	return err
+}